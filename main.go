@@ -3,10 +3,9 @@ package main
 import (
 	"errors"
 	"fmt"
-	"log"
 	"os"
 	"strconv"
-	"strings"
+	"time"
 
 	"kmt/pkg/command"
 	"kmt/pkg/config"
@@ -23,32 +22,101 @@ func main() {
 		Description:            "kmt help",
 		EnableBashCompletion:   true,
 		UseShortOptionHandling: true,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "config",
+				Aliases: []string{"c"},
+				EnvVars: []string{"KMT_CONFIG"},
+				Usage:   "Config file, by default Kmtfile.yml is searched in current and parent directories",
+			},
+			&cli.StringFlag{
+				Name:    "profile",
+				Aliases: []string{"p"},
+				EnvVars: []string{"KMT_PROFILE"},
+				Usage:   "Profile overlay, Kmtfile.<profile>.yml is merged over the config file",
+			},
+		},
 		Commands: []*cli.Command{
 			{
 				Name:        "sync",
 				Aliases:     []string{"sy"},
 				Description: "sync <cluster> <schema>",
 				Usage:       "Set the cluster to latest version",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Show migration files that will be run without touching the database",
+					},
+					&cli.IntFlag{
+						Name:  "parallel",
+						Value: 1,
+						Usage: "Number of nodes migrated at the same time",
+					},
+					&cli.BoolFlag{
+						Name:  "continue-on-error",
+						Usage: "Keep migrating remaining nodes when a node fails",
+					},
+					&cli.StringFlag{
+						Name:  "strategy",
+						Value: config.STRATEGY_ALL,
+						Usage: "Sync strategy, 'all' or 'canary'",
+					},
+					&cli.StringFlag{
+						Name:  "canary",
+						Usage: "Node migrated and verified first when using canary strategy",
+					},
+					&cli.IntFlag{
+						Name:  "batch",
+						Usage: "Number of nodes per batch after the canary is verified",
+					},
+				},
 				Action: func(ctx *cli.Context) error {
 					if ctx.NArg() != 2 {
-						return errors.New("not enough arguments. Usage: kmt sync <cluster> <schema>")
+						return command.Errorf(command.ERROR_INVALID, "not enough arguments. Usage: kmt sync <cluster> <schema>")
 					}
 
-					config := config.Parse(config.CONFIG_FILE)
+					cfg, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+					if err != nil {
+						return command.Wrap(command.ERROR_INVALID, err)
+					}
+
+					if ctx.Bool("dry-run") {
+						return command.NewPlan(cfg.Migration).Sync(ctx.Args().Get(0), ctx.Args().Get(1))
+					}
 
-					return command.NewSync(config.Migration).Run(ctx.Args().Get(0), ctx.Args().Get(1))
+					switch ctx.String("strategy") {
+					case config.STRATEGY_ALL:
+						return command.NewSync(cfg.Migration).Run(ctx.Args().Get(0), ctx.Args().Get(1), ctx.Int("parallel"), ctx.Bool("continue-on-error"))
+					case config.STRATEGY_CANARY:
+						return command.NewSync(cfg.Migration).Canary(ctx.Args().Get(0), ctx.Args().Get(1), ctx.String("canary"), ctx.Int("batch"))
+					}
+
+					return command.Errorf(command.ERROR_INVALID, "unknown strategy '%s'", ctx.String("strategy"))
 				},
 			},
 			{
 				Name:        "up",
 				Description: "up <db> <schema>",
 				Usage:       "Migration up",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Show migration files that will be run without touching the database",
+					},
+				},
 				Action: func(ctx *cli.Context) error {
 					if ctx.NArg() != 2 {
-						return errors.New("not enough arguments. Usage: kmt up <db> <schema>")
+						return command.Errorf(command.ERROR_INVALID, "not enough arguments. Usage: kmt up <db> <schema>")
 					}
 
-					config := config.Parse(config.CONFIG_FILE)
+					config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+					if err != nil {
+						return command.Wrap(command.ERROR_INVALID, err)
+					}
+
+					if ctx.Bool("dry-run") {
+						return command.NewPlan(config.Migration).Up(ctx.Args().Get(0), ctx.Args().Get(1), 0)
+					}
 
 					return command.NewUp(config.Migration).Call(ctx.Args().Get(0), ctx.Args().Get(1))
 				},
@@ -58,12 +126,25 @@ func main() {
 				Aliases:     []string{"mk"},
 				Description: "make <schema> <source> <destination>",
 				Usage:       "Make schema on the destination has same version with the source",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Show migration files that will be run without touching the database",
+					},
+				},
 				Action: func(ctx *cli.Context) error {
 					if ctx.NArg() != 3 {
-						return errors.New("not enough arguments. Usage: kmt make <schema> <source> <destination>")
+						return command.Errorf(command.ERROR_INVALID, "not enough arguments. Usage: kmt make <schema> <source> <destination>")
 					}
 
-					config := config.Parse(config.CONFIG_FILE)
+					config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+					if err != nil {
+						return command.Wrap(command.ERROR_INVALID, err)
+					}
+
+					if ctx.Bool("dry-run") {
+						return command.NewPlan(config.Migration).Copy(ctx.Args().Get(0), ctx.Args().Get(1), ctx.Args().Get(2))
+					}
 
 					return command.NewCopy(config.Migration).Call(ctx.Args().Get(0), ctx.Args().Get(1), ctx.Args().Get(2))
 				},
@@ -73,19 +154,39 @@ func main() {
 				Aliases:     []string{"rb"},
 				Description: "rollback <db> <schema> <step>",
 				Usage:       "Migration rollback",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "yes",
+						Aliases: []string{"y"},
+						Usage:   "Skip confirmation on protected connections",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Show migration files that will be run without touching the database",
+					},
+				},
 				Action: func(ctx *cli.Context) error {
 					if ctx.NArg() != 3 {
-						return errors.New("not enough arguments. Usage: kmt rollback <db> <schema> <step>")
+						return command.Errorf(command.ERROR_INVALID, "not enough arguments. Usage: kmt rollback <db> <schema> <step>")
 					}
 
-					config := config.Parse(config.CONFIG_FILE)
-					errorColor := color.New(color.FgRed)
+					config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+					if err != nil {
+						return command.Wrap(command.ERROR_INVALID, err)
+					}
 
 					n, err := strconv.ParseInt(ctx.Args().Get(2), 10, 0)
 					if err != nil {
-						errorColor.Println("Step is not number")
+						return command.Errorf(command.ERROR_INVALID, "step is not number")
+					}
 
-						return nil
+					if ctx.Bool("dry-run") {
+						return command.NewPlan(config.Migration).Rollback(ctx.Args().Get(0), ctx.Args().Get(1), int(n))
+					}
+
+					err = command.NewProtection(config.Migration).Confirm(ctx.Args().Get(0), ctx.Args().Get(1), command.ACTION_ROLLBACK, ctx.Bool("yes"))
+					if err != nil {
+						return err
 					}
 
 					return command.NewRollback(config.Migration).Call(ctx.Args().Get(0), ctx.Args().Get(1), int(n))
@@ -96,19 +197,29 @@ func main() {
 				Aliases:     []string{"rn"},
 				Description: "run <db> <schema> <step>",
 				Usage:       "Run migration for n steps",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Show migration files that will be run without touching the database",
+					},
+				},
 				Action: func(ctx *cli.Context) error {
 					if ctx.NArg() != 3 {
-						return errors.New("not enough arguments. Usage: kmt run <db> <schema> <step>")
+						return command.Errorf(command.ERROR_INVALID, "not enough arguments. Usage: kmt run <db> <schema> <step>")
 					}
 
-					config := config.Parse(config.CONFIG_FILE)
-					errorColor := color.New(color.FgRed)
+					config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+					if err != nil {
+						return command.Wrap(command.ERROR_INVALID, err)
+					}
 
 					n, err := strconv.ParseInt(ctx.Args().Get(2), 10, 0)
 					if err != nil {
-						errorColor.Println("Step is not number")
+						return command.Errorf(command.ERROR_INVALID, "step is not number")
+					}
 
-						return nil
+					if ctx.Bool("dry-run") {
+						return command.NewPlan(config.Migration).Run(ctx.Args().Get(0), ctx.Args().Get(1), int(n))
 					}
 
 					return command.NewRun(config.Migration).Call(ctx.Args().Get(0), ctx.Args().Get(1), int(n))
@@ -119,19 +230,31 @@ func main() {
 				Aliases:     []string{"st"},
 				Description: "set <db> <schema> <version>",
 				Usage:       "Set migration to specific version",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "yes",
+						Aliases: []string{"y"},
+						Usage:   "Skip confirmation on protected connections",
+					},
+				},
 				Action: func(ctx *cli.Context) error {
 					if ctx.NArg() != 3 {
-						return errors.New("not enough arguments. Usage: kmt set <db> <schema> <version>")
+						return command.Errorf(command.ERROR_INVALID, "not enough arguments. Usage: kmt set <db> <schema> <version>")
 					}
 
-					config := config.Parse(config.CONFIG_FILE)
-					errorColor := color.New(color.FgRed)
+					config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+					if err != nil {
+						return command.Wrap(command.ERROR_INVALID, err)
+					}
 
 					n, err := strconv.ParseInt(ctx.Args().Get(2), 10, 0)
 					if err != nil {
-						errorColor.Println("Version is not number")
+						return command.Errorf(command.ERROR_INVALID, "version is not number")
+					}
 
-						return nil
+					err = command.NewProtection(config.Migration).Confirm(ctx.Args().Get(0), ctx.Args().Get(1), command.ACTION_SET, ctx.Bool("yes"))
+					if err != nil {
+						return err
 					}
 
 					return command.NewSet(config.Migration).Call(ctx.Args().Get(0), ctx.Args().Get(1), int(n))
@@ -142,19 +265,29 @@ func main() {
 				Aliases:     []string{"mg"},
 				Description: "migrate <db> <schema> <version>",
 				Usage:       "Migrate schema to specific version",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Show migration files that will be run without touching the database",
+					},
+				},
 				Action: func(ctx *cli.Context) error {
 					if ctx.NArg() != 3 {
-						return errors.New("not enough arguments. Usage: kmt migrate <db> <schema> <version>")
+						return command.Errorf(command.ERROR_INVALID, "not enough arguments. Usage: kmt migrate <db> <schema> <version>")
 					}
 
-					config := config.Parse(config.CONFIG_FILE)
-					errorColor := color.New(color.FgRed)
+					config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+					if err != nil {
+						return command.Wrap(command.ERROR_INVALID, err)
+					}
 
 					n, err := strconv.ParseInt(ctx.Args().Get(2), 10, 0)
 					if err != nil {
-						errorColor.Println("Version is not number")
+						return command.Errorf(command.ERROR_INVALID, "version is not number")
+					}
 
-						return nil
+					if ctx.Bool("dry-run") {
+						return command.NewPlan(config.Migration).Migrate(ctx.Args().Get(0), ctx.Args().Get(1), uint(n))
 					}
 
 					return command.NewMigrate(config.Migration).Call(ctx.Args().Get(0), ctx.Args().Get(1), int(n))
@@ -165,12 +298,27 @@ func main() {
 				Aliases:     []string{"dp"},
 				Description: "drop <db> <schema>",
 				Usage:       "Drop migration",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "yes",
+						Aliases: []string{"y"},
+						Usage:   "Skip confirmation on protected connections",
+					},
+				},
 				Action: func(ctx *cli.Context) error {
 					if ctx.NArg() != 2 {
-						return errors.New("not enough arguments. Usage: kmt drop <db> <schema>")
+						return command.Errorf(command.ERROR_INVALID, "not enough arguments. Usage: kmt drop <db> <schema>")
 					}
 
-					config := config.Parse(config.CONFIG_FILE)
+					config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+					if err != nil {
+						return command.Wrap(command.ERROR_INVALID, err)
+					}
+
+					err = command.NewProtection(config.Migration).Confirm(ctx.Args().Get(0), ctx.Args().Get(1), command.ACTION_DROP, ctx.Bool("yes"))
+					if err != nil {
+						return err
+					}
 
 					return command.NewDown(config.Migration).Call(ctx.Args().Get(0), ctx.Args().Get(1))
 				},
@@ -180,27 +328,94 @@ func main() {
 				Aliases:     []string{"cl"},
 				Description: "clean <db> <schema>",
 				Usage:       "Clean dirty migration",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "yes",
+						Aliases: []string{"y"},
+						Usage:   "Skip confirmation on protected connections",
+					},
+				},
 				Action: func(ctx *cli.Context) error {
 					if ctx.NArg() != 2 {
-						return errors.New("not enough arguments. Usage: kmt clean <db> <schema>")
+						return command.Errorf(command.ERROR_INVALID, "not enough arguments. Usage: kmt clean <db> <schema>")
 					}
 
-					config := config.Parse(config.CONFIG_FILE)
+					config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+					if err != nil {
+						return command.Wrap(command.ERROR_INVALID, err)
+					}
+
+					err = command.NewProtection(config.Migration).Confirm(ctx.Args().Get(0), ctx.Args().Get(1), command.ACTION_CLEAN, ctx.Bool("yes"))
+					if err != nil {
+						return err
+					}
 
 					return command.NewClean(config.Migration).Call(ctx.Args().Get(0), ctx.Args().Get(1))
 				},
 			},
+			{
+				Name:        "restore",
+				Aliases:     []string{"rs"},
+				Description: "restore <db> <schema> <backup>",
+				Usage:       "Restore schema from backup taken before migration",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "yes",
+						Aliases: []string{"y"},
+						Usage:   "Skip confirmation on protected connections",
+					},
+				},
+				Action: func(ctx *cli.Context) error {
+					if ctx.NArg() != 3 {
+						return command.Errorf(command.ERROR_INVALID, "not enough arguments. Usage: kmt restore <db> <schema> <backup>")
+					}
+
+					config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+					if err != nil {
+						return command.Wrap(command.ERROR_INVALID, err)
+					}
+
+					err = command.NewProtection(config.Migration).Confirm(ctx.Args().Get(0), ctx.Args().Get(1), command.ACTION_RESTORE, ctx.Bool("yes"))
+					if err != nil {
+						return err
+					}
+
+					return command.NewRestore(config.Migration).Call(ctx.Args().Get(0), ctx.Args().Get(1), ctx.Args().Get(2))
+				},
+			},
 			{
 				Name:        "create",
 				Aliases:     []string{"cr"},
-				Description: "create <schema> <name>",
+				Description: "create <schema> <name> or create --from-diff <source> <target> <schema> <name>",
 				Usage:       "Create new migration files for schema",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "from-diff",
+						Usage: "Generate migration that turn target structure into source structure",
+					},
+				},
 				Action: func(ctx *cli.Context) error {
+					if ctx.Bool("from-diff") {
+						if ctx.NArg() != 4 {
+							return command.Errorf(command.ERROR_INVALID, "not enough arguments. Usage: kmt create --from-diff <source> <target> <schema> <name>")
+						}
+
+						config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+						if err != nil {
+							return command.Wrap(command.ERROR_INVALID, err)
+						}
+
+						return command.NewCreate(config.Migration).Diff(ctx.Args().Get(0), ctx.Args().Get(1), ctx.Args().Get(2), ctx.Args().Get(3))
+					}
+
 					if ctx.NArg() != 2 {
-						return errors.New("not enough arguments. Usage: kmt create <schema> <name>")
+						return command.Errorf(command.ERROR_INVALID, "not enough arguments. Usage: kmt create <schema> <name>")
 					}
 
-					config := config.Parse(config.CONFIG_FILE)
+					config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+					if err != nil {
+						return command.Wrap(command.ERROR_INVALID, err)
+					}
 
 					return command.NewCreate(config.Migration).Call(ctx.Args().Get(0), ctx.Args().Get(1))
 				},
@@ -211,15 +426,18 @@ func main() {
 				Description: "generate [<schema>]",
 				Usage:       "Generate migrations from existing database (reverse migration)",
 				Action: func(ctx *cli.Context) error {
-					cfg := config.Parse(config.CONFIG_FILE)
+					cfg, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+					if err != nil {
+						return command.Wrap(command.ERROR_INVALID, err)
+					}
 					source, ok := cfg.Migration.Connections[cfg.Migration.Source]
 					if !ok {
-						return fmt.Errorf("source '%s' not found", cfg.Migration.Source)
+						return command.Errorf(command.ERROR_NOT_FOUND, "source '%s' not found", cfg.Migration.Source)
 					}
 
 					db, err := config.NewConnection(source)
 					if err != nil {
-						return err
+						return command.Wrap(command.ERROR_CONNECTION, err)
 					}
 
 					cmd := command.NewGenerate(cfg.Migration, db)
@@ -227,11 +445,17 @@ func main() {
 						return cmd.Call(ctx.Args().Get(0))
 					}
 
+					var result error
 					for k := range source.Schemas {
-						cmd.Call(k)
+						err := cmd.Call(k)
+						if err != nil {
+							color.New(color.FgRed).Println(err.Error())
+
+							result = err
+						}
 					}
 
-					return nil
+					return result
 				},
 			},
 			{
@@ -241,10 +465,13 @@ func main() {
 				Usage:       "Show migration version",
 				Action: func(ctx *cli.Context) error {
 					if ctx.NArg() < 1 {
-						return errors.New("not enough arguments. Usage: kmt version <db>/<cluster> [<schema>]")
+						return command.Errorf(command.ERROR_INVALID, "not enough arguments. Usage: kmt version <db>/<cluster> [<schema>]")
 					}
 
-					config := config.Parse(config.CONFIG_FILE)
+					config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+					if err != nil {
+						return command.Wrap(command.ERROR_INVALID, err)
+					}
 					cmd := command.NewVersion(config.Migration)
 
 					t := table.NewWriter()
@@ -254,23 +481,19 @@ func main() {
 					if ctx.NArg() == 2 {
 						db := ctx.Args().Get(0)
 						schema := ctx.Args().Get(1)
-						version, diff := cmd.Call(db, schema)
-						if version == 0 {
-							return nil
+						version, diff, err := cmd.Call(db, schema)
+						if err != nil {
+							return err
 						}
 
-						files, err := os.ReadDir(fmt.Sprintf("%s/%s", config.Migration.Folder, schema))
+						index, err := command.NewIndex(config.Migration.Folder, schema)
 						if err != nil {
-							fmt.Println(err.Error())
-
-							return nil
+							return err
 						}
 
-						tFiles := len(files)
-						file := strings.Split(files[tFiles-1].Name(), "_")
-						v, _ := strconv.Atoi(file[0])
+						v := index.Latest()
 
-						sync := uint(v) == version
+						sync := v == version
 						var status string
 						if sync {
 							status = color.New(color.FgGreen).Sprint("✔")
@@ -292,27 +515,23 @@ func main() {
 					if !ok {
 						source, ok := config.Migration.Connections[db]
 						if !ok {
-							return fmt.Errorf("cluster/connection '%s' not found", db)
+							return command.Errorf(command.ERROR_NOT_FOUND, "cluster/connection '%s' not found", db)
 						}
 
 						for k := range source.Schemas {
-							version, diff := cmd.Call(db, k)
-							if version == 0 {
-								return nil
+							version, diff, err := cmd.Call(db, k)
+							if err != nil {
+								return err
 							}
 
-							files, err := os.ReadDir(fmt.Sprintf("%s/%s", config.Migration.Folder, k))
+							index, err := command.NewIndex(config.Migration.Folder, k)
 							if err != nil {
-								fmt.Println(err.Error())
-
-								return nil
+								return err
 							}
 
-							tFiles := len(files)
-							file := strings.Split(files[tFiles-1].Name(), "_")
-							v, _ := strconv.Atoi(file[0])
+							v := index.Latest()
 
-							sync := uint(v) == version
+							sync := v == version
 							var status string
 							if sync {
 								status = color.New(color.FgGreen).Sprint("✔")
@@ -335,27 +554,23 @@ func main() {
 					for _, c := range clusters {
 						source, ok := config.Migration.Connections[c]
 						if !ok {
-							return fmt.Errorf("connection for '%s' not found", c)
+							return command.Errorf(command.ERROR_NOT_FOUND, "connection for '%s' not found", c)
 						}
 
 						for k := range source.Schemas {
-							version, diff := cmd.Call(c, k)
-							if version == 0 {
-								return nil
+							version, diff, err := cmd.Call(c, k)
+							if err != nil {
+								return err
 							}
 
-							files, err := os.ReadDir(fmt.Sprintf("%s/%s", config.Migration.Folder, k))
+							index, err := command.NewIndex(config.Migration.Folder, k)
 							if err != nil {
-								fmt.Println(err.Error())
-
-								return nil
+								return err
 							}
 
-							tFiles := len(files)
-							file := strings.Split(files[tFiles-1].Name(), "_")
-							v, _ := strconv.Atoi(file[0])
+							v := index.Latest()
 
-							sync := uint(v) == version
+							sync := v == version
 							var status string
 							if sync {
 								status = color.New(color.FgGreen).Sprint("✔")
@@ -376,6 +591,87 @@ func main() {
 					return nil
 				},
 			},
+			{
+				Name:        "history",
+				Aliases:     []string{"hs"},
+				Description: "history <db> <schema>",
+				Usage:       "Show migration history",
+				Action: func(ctx *cli.Context) error {
+					if ctx.NArg() != 2 {
+						return command.Errorf(command.ERROR_INVALID, "not enough arguments. Usage: kmt history <db> <schema>")
+					}
+
+					config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+					if err != nil {
+						return command.Wrap(command.ERROR_INVALID, err)
+					}
+
+					histories, err := command.NewHistory(config.Migration).Call(ctx.Args().Get(0), ctx.Args().Get(1))
+					if err != nil {
+						return err
+					}
+
+					t := table.NewWriter()
+					t.SetOutputMirror(os.Stdout)
+					t.AppendHeader(table.Row{"No", "Version", "Direction", "Migration File", "Checksum", "User", "Host", "Started At", "Duration", "Status"})
+
+					for i, h := range histories {
+						var status string
+						if h.Success {
+							status = color.New(color.FgGreen).Sprint("✔")
+						} else {
+							status = color.New(color.FgRed, color.Bold).Sprintf("x %s", h.Message)
+						}
+
+						checksum := h.Checksum
+						if len(checksum) > 12 {
+							checksum = checksum[:12]
+						}
+
+						t.AppendRow(table.Row{i + 1, h.Version, h.Direction, h.File, checksum, h.User, h.Host, h.StartedAt.Local().Format(time.RFC3339), h.FinishedAt.Sub(h.StartedAt).Round(time.Millisecond), status})
+					}
+
+					t.Render()
+
+					return nil
+				},
+			},
+			{
+				Name:        "status",
+				Aliases:     []string{"ss"},
+				Description: "status <db>/<cluster> [<schema>]",
+				Usage:       "Show the state of every migration file",
+				Action: func(ctx *cli.Context) error {
+					if ctx.NArg() < 1 {
+						return command.Errorf(command.ERROR_INVALID, "not enough arguments. Usage: kmt status <db>/<cluster> [<schema>]")
+					}
+
+					config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+					if err != nil {
+						return command.Wrap(command.ERROR_INVALID, err)
+					}
+
+					return command.NewStatus(config.Migration).Call(ctx.Args().Get(0), ctx.Args().Get(1))
+				},
+			},
+			{
+				Name:        "verify",
+				Aliases:     []string{"vf"},
+				Description: "verify <db>/<cluster> <schema>",
+				Usage:       "Verify applied migration files have not been modified",
+				Action: func(ctx *cli.Context) error {
+					if ctx.NArg() != 2 {
+						return command.Errorf(command.ERROR_INVALID, "not enough arguments. Usage: kmt verify <db>/<cluster> <schema>")
+					}
+
+					config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+					if err != nil {
+						return command.Wrap(command.ERROR_INVALID, err)
+					}
+
+					return command.NewVerify(config.Migration).Call(ctx.Args().Get(0), ctx.Args().Get(1))
+				},
+			},
 			{
 				Name:        "compare",
 				Aliases:     []string{"c"},
@@ -383,10 +679,13 @@ func main() {
 				Usage:       "Compare migration from dbs",
 				Action: func(ctx *cli.Context) error {
 					if ctx.NArg() < 2 {
-						return errors.New("not enough arguments. Usage: kmt compare <source> <compare> [<schema>]")
+						return command.Errorf(command.ERROR_INVALID, "not enough arguments. Usage: kmt compare <source> <compare> [<schema>]")
 					}
 
-					config := config.Parse(config.CONFIG_FILE)
+					config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+					if err != nil {
+						return command.Wrap(command.ERROR_INVALID, err)
+					}
 					cmd := command.NewCompare(config.Migration)
 
 					t := table.NewWriter()
@@ -394,12 +693,12 @@ func main() {
 
 					source, ok := config.Migration.Connections[ctx.Args().Get(0)]
 					if !ok {
-						return fmt.Errorf("connection '%s' not found", ctx.Args().Get(0))
+						return command.Errorf(command.ERROR_NOT_FOUND, "connection '%s' not found", ctx.Args().Get(0))
 					}
 
 					compare, ok := config.Migration.Connections[ctx.Args().Get(1)]
 					if !ok {
-						return fmt.Errorf("connection '%s' not found", ctx.Args().Get(1))
+						return command.Errorf(command.ERROR_NOT_FOUND, "connection '%s' not found", ctx.Args().Get(1))
 					}
 
 					t.AppendHeader(table.Row{"No", "Schema", fmt.Sprintf("%s Version", ctx.Args().Get(0)), fmt.Sprintf("%s Version", ctx.Args().Get(1)), "Sync", "Diff"})
@@ -408,17 +707,17 @@ func main() {
 						schema := ctx.Args().Get(2)
 						_, ok := source.Schemas[schema]
 						if !ok {
-							return fmt.Errorf("schema '%s' not found on %s", schema, ctx.Args().Get(0))
+							return command.Errorf(command.ERROR_NOT_FOUND, "schema '%s' not found on %s", schema, ctx.Args().Get(0))
 						}
 
 						_, ok = compare.Schemas[schema]
 						if !ok {
-							return fmt.Errorf("schema '%s' not found on %s", schema, ctx.Args().Get(1))
+							return command.Errorf(command.ERROR_NOT_FOUND, "schema '%s' not found on %s", schema, ctx.Args().Get(1))
 						}
 
-						vSource, vCompare, diff := cmd.Call(ctx.Args().Get(0), ctx.Args().Get(1), schema)
-						if vSource == 0 {
-							return nil
+						vSource, vCompare, diff, err := cmd.Call(ctx.Args().Get(0), ctx.Args().Get(1), schema)
+						if err != nil {
+							return err
 						}
 
 						sync := vSource == vCompare
@@ -444,23 +743,19 @@ func main() {
 								continue
 							}
 
-							vSource, vCompare, diff := cmd.Call(ctx.Args().Get(0), ctx.Args().Get(1), k)
-							if vSource == 0 {
-								return nil
+							vSource, vCompare, diff, err := cmd.Call(ctx.Args().Get(0), ctx.Args().Get(1), k)
+							if err != nil {
+								return err
 							}
 
-							files, err := os.ReadDir(fmt.Sprintf("%s/%s", config.Migration.Folder, k))
+							index, err := command.NewIndex(config.Migration.Folder, k)
 							if err != nil {
-								fmt.Println(err.Error())
-
-								return nil
+								return err
 							}
 
-							tFiles := len(files)
-							file := strings.Split(files[tFiles-1].Name(), "_")
-							version, _ := strconv.Atoi(file[0])
+							version := index.Latest()
 
-							sync := uint(version) == vSource && vSource == vCompare
+							sync := version == vSource && vSource == vCompare
 							var status string
 							if sync {
 								status = color.New(color.FgGreen).Sprint("✔")
@@ -481,17 +776,194 @@ func main() {
 					return nil
 				},
 			},
+			{
+				Name:        "diff",
+				Aliases:     []string{"df"},
+				Description: "diff <source> <compare> [<schema>]",
+				Usage:       "Compare schema structure from dbs",
+				Action: func(ctx *cli.Context) error {
+					if ctx.NArg() < 2 {
+						return command.Errorf(command.ERROR_INVALID, "not enough arguments. Usage: kmt diff <source> <compare> [<schema>]")
+					}
+
+					config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+					if err != nil {
+						return command.Wrap(command.ERROR_INVALID, err)
+					}
+
+					return command.NewDiff(config.Migration).Call(ctx.Args().Get(0), ctx.Args().Get(1), ctx.Args().Get(2))
+				},
+			},
+			{
+				Name:        "drift",
+				Aliases:     []string{"dr"},
+				Description: "drift <db> <schema>",
+				Usage:       "Detect changes made outside migrations using shadow database",
+				Action: func(ctx *cli.Context) error {
+					if ctx.NArg() != 2 {
+						return command.Errorf(command.ERROR_INVALID, "not enough arguments. Usage: kmt drift <db> <schema>")
+					}
+
+					config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+					if err != nil {
+						return command.Wrap(command.ERROR_INVALID, err)
+					}
+
+					return command.NewDrift(config.Migration).Call(ctx.Args().Get(0), ctx.Args().Get(1))
+				},
+			},
 			{
 				Name:        "test",
 				Aliases:     []string{"t"},
 				Description: "test",
 				Usage:       "Test kmt configuration",
 				Action: func(ctx *cli.Context) error {
-					config := config.Parse(config.CONFIG_FILE)
+					config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+					if err != nil {
+						return command.Wrap(command.ERROR_INVALID, err)
+					}
 
 					return command.NewTest(config.Migration).Call()
 				},
 			},
+			{
+				Name:        "check",
+				Aliases:     []string{"ck"},
+				Description: "check [schema]",
+				Usage:       "Validate migration folders",
+				Action: func(ctx *cli.Context) error {
+					config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+					if err != nil {
+						return command.Wrap(command.ERROR_INVALID, err)
+					}
+
+					return command.NewCheck(config.Migration).Call(ctx.Args().Get(0))
+				},
+			},
+			{
+				Name:        "lint",
+				Aliases:     []string{"ln"},
+				Description: "lint [schema]",
+				Usage:       "Flag risky patterns in migration files",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Value: command.LINT_FORMAT_TEXT,
+						Usage: "Output format, 'text' or 'json'",
+					},
+				},
+				Action: func(ctx *cli.Context) error {
+					config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+					if err != nil {
+						return command.Wrap(command.ERROR_INVALID, err)
+					}
+
+					return command.NewLint(config.Migration).Call(ctx.Args().Get(0), ctx.String("format"))
+				},
+			},
+			{
+				Name:        "lock",
+				Aliases:     []string{"lk"},
+				Description: "lock status|release",
+				Usage:       "Manage migration locks",
+				Subcommands: []*cli.Command{
+					{
+						Name:        "status",
+						Description: "status <db|cluster> [schema]",
+						Usage:       "Show migration locks held on a connection or cluster",
+						Action: func(ctx *cli.Context) error {
+							if ctx.NArg() < 1 {
+								return command.Errorf(command.ERROR_INVALID, "not enough arguments. Usage: kmt lock status <db|cluster> [schema]")
+							}
+
+							config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+							if err != nil {
+								return command.Wrap(command.ERROR_INVALID, err)
+							}
+
+							return command.NewLock(config.Migration).Status(ctx.Args().Get(0), ctx.Args().Get(1))
+						},
+					},
+					{
+						Name:        "release",
+						Description: "release <db|cluster> <schema>",
+						Usage:       "Release a stuck migration lock",
+						Action: func(ctx *cli.Context) error {
+							if ctx.NArg() != 2 {
+								return command.Errorf(command.ERROR_INVALID, "not enough arguments. Usage: kmt lock release <db|cluster> <schema>")
+							}
+
+							config, err := config.Parse(ctx.String("config"), ctx.String("profile"))
+							if err != nil {
+								return command.Wrap(command.ERROR_INVALID, err)
+							}
+
+							return command.NewLock(config.Migration).Release(ctx.Args().Get(0), ctx.Args().Get(1))
+						},
+					},
+				},
+			},
+			{
+				Name:        "config",
+				Aliases:     []string{"cf"},
+				Description: "config validate",
+				Usage:       "Manage kmt configuration",
+				Subcommands: []*cli.Command{
+					{
+						Name:        "validate",
+						Description: "validate",
+						Usage:       "Validate kmt configuration",
+						Action: func(ctx *cli.Context) error {
+							path, err := config.Find(ctx.String("config"))
+							if err != nil {
+								return command.Wrap(command.ERROR_INVALID, err)
+							}
+
+							_, err = config.Parse(path, ctx.String("profile"))
+							if err != nil {
+								return command.Wrap(command.ERROR_INVALID, err)
+							}
+
+							color.New(color.FgGreen).Printf("Config %s is valid\n", color.New(color.Bold).Sprint(path))
+
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:        "init",
+				Aliases:     []string{"i"},
+				Description: "init [--connection <name>=<url>]... [--schema <schema>]... [--generate]",
+				Usage:       "Create Kmtfile.yml and migration folders for a new project",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:  "connection",
+						Usage: "Connection as <name>=postgres://<user>:<password>@<host>:<port>/<database>, the first one is the source, skips the questions",
+					},
+					&cli.StringSliceFlag{
+						Name:  "schema",
+						Usage: "Schema to manage, by default all schemas on the source",
+					},
+					&cli.StringFlag{
+						Name:  "folder",
+						Value: "migrations",
+						Usage: "Migration folder",
+					},
+					&cli.BoolFlag{
+						Name:  "generate",
+						Usage: "Generate migrations from the source after writing the config",
+					},
+				},
+				Action: func(ctx *cli.Context) error {
+					path := ctx.String("config")
+					if path == "" {
+						path = config.CONFIG_FILE
+					}
+
+					return command.NewInit().Call(path, ctx.String("folder"), ctx.StringSlice("connection"), ctx.StringSlice("schema"), ctx.Bool("generate"))
+				},
+			},
 			{
 				Name:        "upgrade",
 				Aliases:     []string{"u"},
@@ -517,9 +989,20 @@ func main() {
 				},
 			},
 		},
+		ExitErrHandler: func(ctx *cli.Context, err error) {},
 	}
 
-	if err := app.Run(os.Args); err != nil {
-		log.Fatal(err)
+	err := app.Run(os.Args)
+	if err == nil {
+		return
 	}
+
+	color.New(color.FgRed).Fprintln(os.Stderr, err.Error())
+
+	var e command.Error
+	if errors.As(err, &e) {
+		os.Exit(e.ExitCode())
+	}
+
+	os.Exit(1)
 }
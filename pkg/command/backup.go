@@ -0,0 +1,83 @@
+package command
+
+import (
+	"fmt"
+	"kmt/pkg/config"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+type backup struct {
+	config       config.Migration
+	boldFont     *color.Color
+	errorColor   *color.Color
+	successColor *color.Color
+}
+
+const BACKUP_EXTENSION = ".dump"
+
+func NewBackup(config config.Migration) backup {
+	return backup{
+		config:       config,
+		boldFont:     color.New(color.Bold),
+		errorColor:   color.New(color.FgRed),
+		successColor: color.New(color.FgGreen),
+	}
+}
+
+func (b backup) Call(source string, schema string) (string, error) {
+	dbConfig, ok := b.config.Connections[source]
+	if !ok {
+		return "", Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", source)
+	}
+
+	folder := b.folder(source, schema)
+	err := os.MkdirAll(folder, 0777)
+	if err != nil {
+		return "", err
+	}
+
+	path := fmt.Sprintf("%s/%s%s", folder, time.Now().Format("20060102150405"), BACKUP_EXTENSION)
+
+	cli := exec.Command(b.config.PgDump, "--format", "custom", "--schema", schema, "--file", path, "--dbname", dbConfig.Conninfo())
+	cli.Env = append(os.Environ(), fmt.Sprintf("PGPASSWORD=%s", dbConfig.Secret()))
+
+	output, err := cli.CombinedOutput()
+	if err != nil {
+		os.Remove(path)
+
+		return "", fmt.Errorf("backup of %s schema %s failed: %w: %s", source, schema, err, strings.TrimSpace(string(output)))
+	}
+
+	b.successColor.Printf("Backup of %s schema %s saved to %s\n", b.boldFont.Sprint(source), b.boldFont.Sprint(schema), b.boldFont.Sprint(path))
+
+	return path, nil
+}
+
+func (b backup) folder(source string, schema string) string {
+	return fmt.Sprintf("%s/%s/%s", b.config.BackupFolder, source, schema)
+}
+
+func (b backup) hook(source string, schema string) func() error {
+	if !b.config.Backup {
+		return nil
+	}
+
+	done := false
+
+	return func() error {
+		if done {
+			return nil
+		}
+
+		done = true
+
+		_, err := b.Call(source, schema)
+
+		return err
+	}
+}
@@ -0,0 +1,57 @@
+package command
+
+import (
+	"kmt/pkg/config"
+
+	"github.com/fatih/color"
+)
+
+type check struct {
+	config       config.Migration
+	boldFont     *color.Color
+	errorColor   *color.Color
+	successColor *color.Color
+}
+
+func NewCheck(config config.Migration) check {
+	return check{
+		config:       config,
+		boldFont:     color.New(color.Bold),
+		errorColor:   color.New(color.FgRed),
+		successColor: color.New(color.FgGreen),
+	}
+}
+
+func (c check) Call(schema string) error {
+	schemas, err := schemaFolders(c.config.Folder, schema)
+	if err != nil {
+		return err
+	}
+
+	invalid := 0
+	for _, s := range schemas {
+		i, err := scan(c.config.Folder, s)
+		if err != nil {
+			return err
+		}
+
+		if len(i.problems) == 0 {
+			c.successColor.Printf("Schema %s has %s valid migration(s)\n", c.boldFont.Sprint(s), c.boldFont.Sprint(len(i.versions)))
+
+			continue
+		}
+
+		invalid++
+
+		c.errorColor.Printf("Schema %s has %s problem(s):\n", c.boldFont.Sprint(s), c.boldFont.Sprint(len(i.problems)))
+		for _, p := range i.problems {
+			c.errorColor.Printf("  - %s\n", p)
+		}
+	}
+
+	if invalid > 0 {
+		return Errorf(ERROR_INVALID, "%d schema folder(s) are invalid", invalid)
+	}
+
+	return nil
+}
@@ -26,34 +26,45 @@ func NewClean(config config.Migration) clean {
 func (c clean) Call(source string, schema string) error {
 	dbConfig, ok := c.config.Connections[source]
 	if !ok {
-		c.errorColor.Printf("Database connection '%s' not found\n", c.boldFont.Sprint(source))
-
-		return nil
+		return Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", source)
 	}
 
 	_, ok = dbConfig.Schemas[schema]
 	if !ok {
-		c.errorColor.Printf("Schema '%s' not found\n", schema)
+		return Errorf(ERROR_NOT_FOUND, "schema '%s' not found", schema)
+	}
 
-		return nil
+	_, release, err := NewLock(c.config).Acquire(source, schema)
+	if err != nil {
+		return err
 	}
 
+	defer release()
+
 	db, err := config.NewConnection(dbConfig)
 	if err != nil {
-		c.errorColor.Println(err.Error())
-
-		return nil
+		return Wrap(ERROR_CONNECTION, err)
 	}
 
-	migrator := config.NewMigrator(db, dbConfig.Name, schema, fmt.Sprintf("%s/%s", c.config.Folder, schema))
+	migrator, err := config.NewMigrator(db, dbConfig.Name, schema, fmt.Sprintf("%s/%s", c.config.Folder, schema))
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
+	}
 
 	version, dirty, _ := migrator.Version()
 	if version != 0 && dirty {
-		migrator.Force(int(version))
-		migrator.Steps(-1)
+		err = migrator.Force(int(version))
+		if err != nil {
+			return Wrap(ERROR_MIGRATION, err)
+		}
+
+		err = migrator.Steps(-1)
+		if err != nil {
+			return Wrap(ERROR_MIGRATION, err)
+		}
 	}
 
 	c.successColor.Printf("Migration cleaned on %s schema %s\n", c.boldFont.Sprint(source), c.boldFont.Sprint(schema))
 
-	return err
+	return nil
 }
@@ -3,11 +3,9 @@ package command
 import (
 	"fmt"
 	"kmt/pkg/config"
-	"os"
-	"strconv"
-	"strings"
 
 	"github.com/fatih/color"
+	gomigrate "github.com/golang-migrate/migrate/v4"
 )
 
 type compare struct {
@@ -26,74 +24,64 @@ func NewCompare(config config.Migration) compare {
 	}
 }
 
-func (c compare) Call(source string, compare string, schema string) (uint, uint, int) {
+func (c compare) Call(source string, compare string, schema string) (uint, uint, int, error) {
 	dbSource, ok := c.config.Connections[source]
 	if !ok {
-		c.errorColor.Printf("Database connection '%s' not found\n", c.boldFont.Sprint(source))
-
-		return 0, 0, 0
+		return 0, 0, 0, Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", source)
 	}
 
 	dbCompare, ok := c.config.Connections[compare]
 	if !ok {
-		c.errorColor.Printf("Database connection '%s' not found\n", c.boldFont.Sprint(compare))
-
-		return 0, 0, 0
+		return 0, 0, 0, Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", compare)
 	}
 
 	_, ok = dbSource.Schemas[schema]
 	if !ok {
-		c.errorColor.Printf("Schema '%s' not found on %s\n", c.boldFont.Sprint(schema), c.boldFont.Sprint(source))
-
-		return 0, 0, 0
+		return 0, 0, 0, Errorf(ERROR_NOT_FOUND, "schema '%s' not found on %s", schema, source)
 	}
 
 	_, ok = dbCompare.Schemas[schema]
 	if !ok {
-		c.errorColor.Printf("Schema '%s' not found on %s\n", c.boldFont.Sprint(schema), c.boldFont.Sprint(compare))
-
-		return 0, 0, 0
+		return 0, 0, 0, Errorf(ERROR_NOT_FOUND, "schema '%s' not found on %s", schema, compare)
 	}
 
 	connSource, err := config.NewConnection(dbSource)
 	if err != nil {
-		c.errorColor.Println(err.Error())
-
-		return 0, 0, 0
+		return 0, 0, 0, Wrap(ERROR_CONNECTION, err)
 	}
 
 	connCompare, err := config.NewConnection(dbCompare)
 	if err != nil {
-		c.errorColor.Println(err.Error())
-
-		return 0, 0, 0
+		return 0, 0, 0, Wrap(ERROR_CONNECTION, err)
 	}
 
-	sourceMigrator := config.NewMigrator(connSource, dbSource.Name, schema, fmt.Sprintf("%s/%s", c.config.Folder, schema))
-	sourceVersion, _, err := sourceMigrator.Version()
+	sourceMigrator, err := config.NewMigrator(connSource, dbSource.Name, schema, fmt.Sprintf("%s/%s", c.config.Folder, schema))
 	if err != nil {
-		c.errorColor.Println(err.Error())
+		return 0, 0, 0, Wrap(ERROR_CONNECTION, err)
+	}
 
-		return 0, 0, 0
+	sourceVersion, _, err := sourceMigrator.Version()
+	if err != nil && err != gomigrate.ErrNilVersion {
+		return 0, 0, 0, Wrap(ERROR_MIGRATION, err)
 	}
 
-	compareMigrator := config.NewMigrator(connCompare, dbCompare.Name, schema, fmt.Sprintf("%s/%s", c.config.Folder, schema))
-	compareVersion, _, err := compareMigrator.Version()
+	compareMigrator, err := config.NewMigrator(connCompare, dbCompare.Name, schema, fmt.Sprintf("%s/%s", c.config.Folder, schema))
 	if err != nil {
-		c.errorColor.Println(err.Error())
+		return 0, 0, 0, Wrap(ERROR_CONNECTION, err)
+	}
 
-		return 0, 0, 0
+	compareVersion, _, err := compareMigrator.Version()
+	if err != nil && err != gomigrate.ErrNilVersion {
+		return 0, 0, 0, Wrap(ERROR_MIGRATION, err)
 	}
 
-	files, err := os.ReadDir(fmt.Sprintf("%s/%s", c.config.Folder, schema))
+	i, err := NewIndex(c.config.Folder, schema)
 	if err != nil {
-		c.errorColor.Println(err.Error())
-
-		return 0, 0, 0
+		return 0, 0, 0, err
 	}
 
 	if sourceVersion == compareVersion {
-		return sourceVersion, compareVersion, 0
+		return sourceVersion, compareVersion, 0, nil
 	}
 
 	version := sourceVersion
@@ -102,40 +90,10 @@ func (c compare) Call(source string, compare string, schema string) (uint, uint,
 		version, breakPoint = breakPoint, version
 	}
 
-	tFiles := len(files)
-	file := strings.Split(files[tFiles-1].Name(), "_")
-	vFile, _ := strconv.Atoi(file[0])
-
-	valid := false
-	number := 0
-	for i, file := range files {
-		if i%2 == 0 {
-			continue
-		}
-
-		f := strings.Split(file.Name(), "_")
-		s, _ := strconv.Atoi(f[0])
-		v := uint(s)
-		if v == breakPoint {
-			number++
-
-			break
-		}
-
-		if !valid && (version == v || vFile == s) {
-			valid = true
-
-			continue
-		}
-
-		if valid {
-			number++
-		}
-	}
-
+	number := i.between(version, breakPoint)
 	if compareVersion < sourceVersion {
 		number = number * -1
 	}
 
-	return sourceVersion, compareVersion, number
+	return sourceVersion, compareVersion, number, nil
 }
@@ -27,61 +27,59 @@ func NewCopy(config config.Migration) copy {
 func (c copy) Call(schema string, source string, destination string) error {
 	sourceConfig, ok := c.config.Connections[source]
 	if !ok {
-		c.errorColor.Printf("Database connection '%s' not found\n", c.boldFont.Sprint(source))
-
-		return nil
+		return Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", source)
 	}
 
 	_, ok = sourceConfig.Schemas[schema]
 	if !ok {
-		c.errorColor.Printf("Schema '%s' not found on %s\n", c.boldFont.Sprint(schema), c.boldFont.Sprint(source))
-
-		return nil
+		return Errorf(ERROR_NOT_FOUND, "schema '%s' not found on %s", schema, source)
 	}
 
 	destinationConfig, ok := c.config.Connections[destination]
 	if !ok {
-		c.errorColor.Printf("Database connection '%s' not found\n", c.boldFont.Sprint(destination))
-
-		return nil
+		return Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", destination)
 	}
 
 	_, ok = destinationConfig.Schemas[schema]
 	if !ok {
-		c.errorColor.Printf("Schema '%s' not found on %s\n", c.boldFont.Sprint(schema), c.boldFont.Sprint(destination))
+		return Errorf(ERROR_NOT_FOUND, "schema '%s' not found on %s", schema, destination)
+	}
 
-		return nil
+	ctx, release, err := NewLock(c.config).Acquire(destination, schema)
+	if err != nil {
+		return err
 	}
 
+	defer release()
+
 	sourceDb, err := config.NewConnection(sourceConfig)
 	if err != nil {
-		c.errorColor.Println(err.Error())
-
-		return nil
+		return Wrap(ERROR_CONNECTION, err)
 	}
 
 	destinationDb, err := config.NewConnection(destinationConfig)
 	if err != nil {
-		c.errorColor.Println(err.Error())
+		return Wrap(ERROR_CONNECTION, err)
+	}
 
-		return nil
+	sourceMigrator, err := config.NewMigrator(sourceDb, sourceConfig.Name, schema, fmt.Sprintf("%s/%s", c.config.Folder, schema))
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
 	}
 
-	sourceMigrator := config.NewMigrator(sourceDb, sourceConfig.Name, schema, fmt.Sprintf("%s/%s", c.config.Folder, schema))
-	destinationMigrator := config.NewMigrator(destinationDb, destinationConfig.Name, schema, fmt.Sprintf("%s/%s", c.config.Folder, schema))
+	destinationMigrator, err := config.NewMigrator(destinationDb, destinationConfig.Name, schema, fmt.Sprintf("%s/%s", c.config.Folder, schema))
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
+	}
 
 	sourceVersion, _, err := sourceMigrator.Version()
 	if err != nil {
-		c.errorColor.Println(err.Error())
-
-		return nil
+		return Wrap(ERROR_MIGRATION, err)
 	}
 
 	destinationVersion, _, err := destinationMigrator.Version()
-	if err != nil {
-		c.errorColor.Println(err.Error())
-
-		return nil
+	if err != nil && err != gomigrate.ErrNilVersion {
+		return Wrap(ERROR_MIGRATION, err)
 	}
 
 	if sourceVersion == destinationVersion {
@@ -90,13 +88,19 @@ func (c copy) Call(schema string, source string, destination string) error {
 		return nil
 	}
 
-	err = destinationMigrator.Migrate(sourceVersion)
+	runner := newRunner(c.config.Folder, schema, destinationConfig, destinationDb, destinationMigrator)
+	runner.ctx = ctx
+	err = runner.migrate(sourceVersion)
 	if err != nil && err == gomigrate.ErrNoChange {
 		c.successColor.Printf("Database %s schema %s is up to date\n", c.boldFont.Sprint(source), c.boldFont.Sprint(schema))
 
 		return nil
 	}
 
+	if err != nil {
+		return runner.failure(destination, err)
+	}
+
 	c.successColor.Printf("Migration for schema %s on %s set to %s (same as %s version)\n", c.boldFont.Sprint(schema), c.boldFont.Sprint(destination), c.boldFont.Sprint(sourceVersion), c.boldFont.Sprint(source))
 
 	return nil
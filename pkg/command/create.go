@@ -42,30 +42,44 @@ func (c create) Call(schema string, name string) error {
 	}
 
 	if !valid {
-		c.errorColor.Printf("Schema '%s' not found in all connections\n", c.boldFont.Sprint(schema))
+		return Errorf(ERROR_NOT_FOUND, "schema '%s' not found in all connections", schema)
+	}
+
+	return c.write(schema, name, "", "")
+}
+
+func (c create) Diff(source string, target string, schema string, name string) error {
+	sourceSnapshot, targetSnapshot, err := NewDiff(c.config).snapshots(source, target, schema)
+	if err != nil {
+		return err
+	}
+
+	script := sourceSnapshot.Alter(targetSnapshot)
+	if script.UpScript == "" {
+		c.successColor.Printf("Schema %s on %s has same structure with %s\n", c.boldFont.Sprint(schema), c.boldFont.Sprint(target), c.boldFont.Sprint(source))
 
 		return nil
 	}
 
+	return c.write(schema, name, script.UpScript, script.DownScript)
+}
+
+func (c create) write(schema string, name string, upScript string, downScript string) error {
 	os.MkdirAll(fmt.Sprintf("%s/%s", c.config.Folder, schema), 0777)
 
 	version := time.Now().Unix()
 	name = fmt.Sprintf("%d_%s", version, name)
-	_, err := os.Create(fmt.Sprintf("%s/%s/%s.up.sql", c.config.Folder, schema, name))
+	err := os.WriteFile(fmt.Sprintf("%s/%s/%s.up.sql", c.config.Folder, schema, name), []byte(upScript), 0777)
 	if err != nil {
-		c.errorColor.Println(err.Error())
-
-		return nil
+		return err
 	}
 
-	_, err = os.Create(fmt.Sprintf("%s/%s/%s.down.sql", c.config.Folder, schema, name))
+	err = os.WriteFile(fmt.Sprintf("%s/%s/%s.down.sql", c.config.Folder, schema, name), []byte(downScript), 0777)
 	if err != nil {
-		c.errorColor.Println(err.Error())
-
-		return nil
+		return err
 	}
 
 	c.successColor.Printf("Migration created as %s\n", c.boldFont.Sprint(name))
 
-	return err
+	return nil
 }
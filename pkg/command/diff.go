@@ -0,0 +1,167 @@
+package command
+
+import (
+	"fmt"
+	"kmt/pkg/config"
+	"kmt/pkg/db"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+type diff struct {
+	config       config.Migration
+	boldFont     *color.Color
+	errorColor   *color.Color
+	successColor *color.Color
+}
+
+func NewDiff(config config.Migration) diff {
+	return diff{
+		config:       config,
+		boldFont:     color.New(color.Bold),
+		errorColor:   color.New(color.FgRed),
+		successColor: color.New(color.FgGreen),
+	}
+}
+
+const SUMMARY_LENGTH = 60
+
+func (d diff) Call(source string, compare string, schema string) error {
+	dbSource, ok := d.config.Connections[source]
+	if !ok {
+		return Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", source)
+	}
+
+	dbCompare, ok := d.config.Connections[compare]
+	if !ok {
+		return Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", compare)
+	}
+
+	schemas := []string{schema}
+	if schema == "" {
+		schemas = []string{}
+		for k := range dbSource.Schemas {
+			_, ok := dbCompare.Schemas[k]
+			if ok {
+				schemas = append(schemas, k)
+			}
+		}
+
+		sort.Strings(schemas)
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"No", "Schema", "Object", "Name", source, compare})
+
+	number := 1
+	for _, s := range schemas {
+		sourceSnapshot, compareSnapshot, err := d.snapshots(source, compare, s)
+		if err != nil {
+			return err
+		}
+
+		for _, difference := range sourceSnapshot.Compare(compareSnapshot) {
+			t.AppendRow(append(table.Row{number, s}, row(difference)...))
+
+			number++
+		}
+	}
+
+	if number == 1 {
+		d.successColor.Println("No structural difference found")
+
+		return nil
+	}
+
+	t.Render()
+
+	return nil
+}
+
+func (d diff) snapshots(source string, compare string, schema string) (db.Snapshot, db.Snapshot, error) {
+	dbSource, ok := d.config.Connections[source]
+	if !ok {
+		return db.Snapshot{}, db.Snapshot{}, Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", source)
+	}
+
+	dbCompare, ok := d.config.Connections[compare]
+	if !ok {
+		return db.Snapshot{}, db.Snapshot{}, Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", compare)
+	}
+
+	_, ok = dbSource.Schemas[schema]
+	if !ok {
+		return db.Snapshot{}, db.Snapshot{}, Errorf(ERROR_NOT_FOUND, "schema '%s' not found on %s", schema, source)
+	}
+
+	_, ok = dbCompare.Schemas[schema]
+	if !ok {
+		return db.Snapshot{}, db.Snapshot{}, Errorf(ERROR_NOT_FOUND, "schema '%s' not found on %s", schema, compare)
+	}
+
+	connSource, err := config.NewConnection(dbSource)
+	if err != nil {
+		return db.Snapshot{}, db.Snapshot{}, Wrap(ERROR_CONNECTION, err)
+	}
+
+	defer connSource.Close()
+
+	connCompare, err := config.NewConnection(dbCompare)
+	if err != nil {
+		return db.Snapshot{}, db.Snapshot{}, Wrap(ERROR_CONNECTION, err)
+	}
+
+	defer connCompare.Close()
+
+	sourceSnapshot, err := db.NewSnapshot(connSource).Take(schema)
+	if err != nil {
+		return db.Snapshot{}, db.Snapshot{}, Wrap(ERROR_CONNECTION, err)
+	}
+
+	compareSnapshot, err := db.NewSnapshot(connCompare).Take(schema)
+	if err != nil {
+		return db.Snapshot{}, db.Snapshot{}, Wrap(ERROR_CONNECTION, err)
+	}
+
+	return sourceSnapshot, compareSnapshot, nil
+}
+
+func row(d db.Difference) table.Row {
+	name := d.Name
+	if d.Table != "" {
+		name = fmt.Sprintf("%s.%s", d.Table, d.Name)
+	}
+
+	return table.Row{d.Kind, name, summary(d.Kind, d.Source, d.Target), summary(d.Kind, d.Target, d.Source)}
+}
+
+func summary(kind string, definition string, other string) string {
+	if definition == "" {
+		return color.New(color.FgRed, color.Bold).Sprint("x")
+	}
+
+	if kind != db.OBJECT_FUNCTION && kind != db.OBJECT_VIEW && kind != db.OBJECT_MATERIALIZED_VIEW {
+		return definition
+	}
+
+	if other != "" {
+		return "changed"
+	}
+
+	definition = strings.TrimSpace(definition)
+	line := strings.SplitN(definition, "\n", 2)[0]
+	if len(line) > SUMMARY_LENGTH {
+		line = line[:SUMMARY_LENGTH]
+	}
+
+	if line != definition {
+		line = fmt.Sprintf("%s...", line)
+	}
+
+	return line
+}
@@ -28,32 +28,39 @@ func NewDown(config config.Migration) down {
 func (d down) Call(source string, schema string) error {
 	dbConfig, ok := d.config.Connections[source]
 	if !ok {
-		d.errorColor.Printf("Database connection '%s' not found\n", d.boldFont.Sprint(source))
-
-		return nil
+		return Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", source)
 	}
 
 	_, ok = dbConfig.Schemas[schema]
 	if !ok {
-		d.errorColor.Printf("Schema '%s' not found\n", schema)
+		return Errorf(ERROR_NOT_FOUND, "schema '%s' not found", schema)
+	}
 
-		return nil
+	ctx, release, err := NewLock(d.config).Acquire(source, schema)
+	if err != nil {
+		return err
 	}
 
+	defer release()
+
 	db, err := config.NewConnection(dbConfig)
 	if err != nil {
-		d.errorColor.Println(err.Error())
-
-		return nil
+		return Wrap(ERROR_CONNECTION, err)
 	}
 
-	migrator := config.NewMigrator(db, dbConfig.Name, schema, fmt.Sprintf("%s/%s", d.config.Folder, schema))
+	migrator, err := config.NewMigrator(db, dbConfig.Name, schema, fmt.Sprintf("%s/%s", d.config.Folder, schema))
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
+	}
 
 	progress := spinner.New(spinner.CharSets[config.SPINER_INDEX], config.SPINER_DURATION)
 	progress.Suffix = fmt.Sprintf(" Tear down migrations for %s on %s schema", d.successColor.Sprint(source), d.successColor.Sprint(schema))
 	progress.Start()
 
-	err = migrator.Down()
+	runner := newRunner(d.config.Folder, schema, dbConfig, db, migrator)
+	runner.ctx = ctx
+
+	err = runner.down(0)
 	if err != nil && err == gomigrate.ErrNoChange {
 		progress.Stop()
 
@@ -62,15 +69,15 @@ func (d down) Call(source string, schema string) error {
 		return nil
 	}
 
-	version, dirty, _ := migrator.Version()
-	if version != 0 && dirty {
-		migrator.Force(int(version))
-		migrator.Steps(-1)
+	if err != nil {
+		progress.Stop()
+
+		return runner.failure(source, err)
 	}
 
 	progress.Stop()
 
 	d.successColor.Printf("Migration on %s schema %s tear down successfully\n", d.boldFont.Sprint(source), d.boldFont.Sprint(schema))
 
-	return err
+	return nil
 }
@@ -0,0 +1,189 @@
+package command
+
+import (
+	"database/sql"
+	"fmt"
+	"kmt/pkg/config"
+	"kmt/pkg/db"
+	"os"
+
+	"github.com/briandowns/spinner"
+	"github.com/fatih/color"
+	gomigrate "github.com/golang-migrate/migrate/v4"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+type drift struct {
+	config       config.Migration
+	boldFont     *color.Color
+	errorColor   *color.Color
+	successColor *color.Color
+}
+
+func NewDrift(config config.Migration) drift {
+	return drift{
+		config:       config,
+		boldFont:     color.New(color.Bold),
+		errorColor:   color.New(color.FgRed),
+		successColor: color.New(color.FgGreen),
+	}
+}
+
+func (d drift) Call(source string, schema string) error {
+	differences, err := d.differences(source, schema)
+	if err != nil {
+		return err
+	}
+
+	if len(differences) == 0 {
+		d.successColor.Printf("No drift found on %s schema %s\n", d.boldFont.Sprint(source), d.boldFont.Sprint(schema))
+
+		return nil
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"No", "Object", "Name", "Live", "Migrations"})
+
+	for i, difference := range differences {
+		t.AppendRow(append(table.Row{i + 1}, row(difference)...))
+	}
+
+	t.Render()
+
+	return Errorf(ERROR_DRIFT, "drift found on %s schema %s", source, schema)
+}
+
+func (d drift) differences(source string, schema string) ([]db.Difference, error) {
+	if d.config.Shadow == "" {
+		return nil, Errorf(ERROR_INVALID, "shadow connection is not configured")
+	}
+
+	if d.config.Shadow == source {
+		return nil, Errorf(ERROR_INVALID, "shadow connection can not be the same as '%s'", source)
+	}
+
+	dbConfig, ok := d.config.Connections[source]
+	if !ok {
+		return nil, Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", source)
+	}
+
+	_, ok = dbConfig.Schemas[schema]
+	if !ok {
+		return nil, Errorf(ERROR_NOT_FOUND, "schema '%s' not found on %s", schema, source)
+	}
+
+	shadowConfig, ok := d.config.Connections[d.config.Shadow]
+	if !ok {
+		return nil, Errorf(ERROR_NOT_FOUND, "shadow connection '%s' not found", d.config.Shadow)
+	}
+
+	for name, c := range d.config.Connections {
+		if name != d.config.Shadow && c.Database() == shadowConfig.Database() {
+			return nil, Errorf(ERROR_INVALID, "shadow connection '%s' points to the same database as '%s'", d.config.Shadow, name)
+		}
+	}
+
+	conn, err := config.NewConnection(dbConfig)
+	if err != nil {
+		return nil, Wrap(ERROR_CONNECTION, err)
+	}
+
+	defer conn.Close()
+
+	shadow, err := config.NewConnection(shadowConfig)
+	if err != nil {
+		return nil, Wrap(ERROR_CONNECTION, err)
+	}
+
+	defer shadow.Close()
+
+	migrator, err := config.NewMigrator(conn, dbConfig.Name, schema, fmt.Sprintf("%s/%s", d.config.Folder, schema))
+	if err != nil {
+		return nil, Wrap(ERROR_CONNECTION, err)
+	}
+
+	version, _, err := migrator.Version()
+	if err != nil && err != gomigrate.ErrNilVersion {
+		return nil, Wrap(ERROR_MIGRATION, err)
+	}
+
+	progress := spinner.New(spinner.CharSets[config.SPINER_INDEX], config.SPINER_DURATION)
+	progress.Suffix = fmt.Sprintf(" Replaying migrations for schema %s on shadow %s", d.successColor.Sprint(schema), d.successColor.Sprint(d.config.Shadow))
+	progress.Start()
+
+	_, err = shadow.Exec(fmt.Sprintf("CREATE SCHEMA %s", schema))
+	if err != nil {
+		progress.Stop()
+
+		return nil, Errorf(ERROR_CONNECTION, "unable to create schema %s on shadow %s, drop it if no other drift is running: %s", schema, d.config.Shadow, err.Error())
+	}
+
+	defer shadow.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schema))
+
+	steps, err := d.replay(conn, schema, version)
+	if err != nil {
+		progress.Stop()
+
+		return nil, err
+	}
+
+	shadowMigrator, err := config.NewMigrator(shadow, shadowConfig.Name, schema, fmt.Sprintf("%s/%s", d.config.Folder, schema))
+	if err != nil {
+		progress.Stop()
+
+		return nil, Wrap(ERROR_CONNECTION, err)
+	}
+
+	err = newRunner(d.config.Folder, schema, shadowConfig, shadow, shadowMigrator).run(steps)
+	if err != nil && err != gomigrate.ErrNoChange {
+		progress.Stop()
+
+		return nil, Errorf(ERROR_MIGRATION, "replaying migrations on shadow failed: %s", err.Error())
+	}
+
+	live, err := db.NewSnapshot(conn).Take(schema)
+	if err != nil {
+		progress.Stop()
+
+		return nil, Wrap(ERROR_CONNECTION, err)
+	}
+
+	expected, err := db.NewSnapshot(shadow).Take(schema)
+	if err != nil {
+		progress.Stop()
+
+		return nil, Wrap(ERROR_CONNECTION, err)
+	}
+
+	progress.Stop()
+
+	return live.Compare(expected), nil
+}
+
+func (d drift) replay(conn *sql.DB, schema string, version uint) ([]migrationStep, error) {
+	versions, files, err := migrationFiles(d.config.Folder, schema)
+	if err != nil {
+		return nil, Wrap(ERROR_NOT_FOUND, err)
+	}
+
+	checksums, err := db.NewHistory(conn, schema).Checksums(DIRECTION_REPEATABLE)
+	if err != nil {
+		return nil, Wrap(ERROR_CONNECTION, err)
+	}
+
+	repeatables, err := repeatableSteps(d.config.Folder, schema, map[string]string{})
+	if err != nil {
+		return nil, Wrap(ERROR_NOT_FOUND, err)
+	}
+
+	applied := []migrationStep{}
+	for _, r := range repeatables {
+		checksum, _ := fileChecksum(fmt.Sprintf("%s/%s/%s", d.config.Folder, schema, r.file))
+		if checksums[r.file] == checksum {
+			applied = append(applied, r)
+		}
+	}
+
+	return interleave(migrateSteps(versions, files, 0, version), applied, true), nil
+}
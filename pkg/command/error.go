@@ -0,0 +1,65 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+
+	gomigrate "github.com/golang-migrate/migrate/v4"
+)
+
+type (
+	ErrorKind int
+
+	Error struct {
+		Kind ErrorKind
+		Err  error
+	}
+)
+
+const (
+	ERROR_INVALID ErrorKind = iota + 2
+	ERROR_NOT_FOUND
+	ERROR_CONNECTION
+	ERROR_MIGRATION
+	ERROR_DIRTY
+	ERROR_DRIFT
+	ERROR_ABORTED
+	ERROR_LOCKED
+	ERROR_LINT
+)
+
+func Errorf(kind ErrorKind, format string, args ...interface{}) error {
+	return Error{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+func Wrap(kind ErrorKind, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var e Error
+	if errors.As(err, &e) {
+		return err
+	}
+
+	if kind == ERROR_MIGRATION {
+		var dirty gomigrate.ErrDirty
+		if errors.As(err, &dirty) {
+			kind = ERROR_DIRTY
+		}
+	}
+
+	return Error{Kind: kind, Err: err}
+}
+
+func (e Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
+func (e Error) ExitCode() int {
+	return int(e.Kind)
+}
@@ -6,6 +6,7 @@ import (
 	"kmt/pkg/config"
 	"kmt/pkg/db"
 	"os"
+	"strings"
 	iSync "sync"
 	"time"
 
@@ -30,6 +31,7 @@ type (
 		schema     string
 		schemaOnly bool
 		table      string
+		fail       func(error)
 	}
 )
 
@@ -45,46 +47,46 @@ func NewGenerate(config config.Migration, connection *sql.DB) generate {
 
 func do(cMigration <-chan migration, cDdl chan<- db.Ddl) {
 	for m := range cMigration {
-		script := m.tableTool.Generate(fmt.Sprintf("%s.%s", m.schema, m.table), m.schemaOnly)
-
-		cDdl <- script
-
-		err := os.WriteFile(fmt.Sprintf("%s/%s/%d_table_%s.up.sql", m.folder, m.schema, m.version, m.table), []byte(script.Definition.UpScript), 0777)
-		if err != nil {
-			m.wg.Done()
-
-			return
-		}
-
-		err = os.WriteFile(fmt.Sprintf("%s/%s/%d_table_%s.down.sql", m.folder, m.schema, m.version, m.table), []byte(script.Definition.DownScript), 0777)
+		script, err := m.tableTool.Generate(fmt.Sprintf("%s.%s", m.schema, m.table), m.schemaOnly)
 		if err != nil {
+			m.fail(fmt.Errorf("table %s: %w", m.table, err))
 			m.wg.Done()
 
-			return
+			continue
 		}
 
-		if script.Reference.UpScript == "" {
-			m.wg.Done()
+		cDdl <- script
 
-			return
+		err = m.write(script)
+		if err != nil {
+			m.fail(err)
 		}
 
-		err = os.WriteFile(fmt.Sprintf("%s/%s/%d_primary_key_%s.up.sql", m.folder, m.schema, m.version+1, m.table), []byte(script.Reference.UpScript), 0777)
-		if err != nil {
-			m.wg.Done()
+		m.wg.Done()
+	}
+}
 
-			return
-		}
+func (m migration) write(script db.Ddl) error {
+	err := os.WriteFile(fmt.Sprintf("%s/%s/%d_table_%s.up.sql", m.folder, m.schema, m.version, m.table), []byte(script.Definition.UpScript), 0777)
+	if err != nil {
+		return err
+	}
 
-		err = os.WriteFile(fmt.Sprintf("%s/%s/%d_primary_key_%s.down.sql", m.folder, m.schema, m.version+1, m.table), []byte(script.Reference.DownScript), 0777)
-		if err != nil {
-			m.wg.Done()
+	err = os.WriteFile(fmt.Sprintf("%s/%s/%d_table_%s.down.sql", m.folder, m.schema, m.version, m.table), []byte(script.Definition.DownScript), 0777)
+	if err != nil {
+		return err
+	}
 
-			return
-		}
+	if script.Reference.UpScript == "" {
+		return nil
+	}
 
-		m.wg.Done()
+	err = os.WriteFile(fmt.Sprintf("%s/%s/%d_primary_key_%s.up.sql", m.folder, m.schema, m.version+1, m.table), []byte(script.Reference.UpScript), 0777)
+	if err != nil {
+		return err
 	}
+
+	return os.WriteFile(fmt.Sprintf("%s/%s/%d_primary_key_%s.down.sql", m.folder, m.schema, m.version+1, m.table), []byte(script.Reference.DownScript), 0777)
 }
 
 func (g generate) Call(schema string) error {
@@ -94,61 +96,144 @@ func (g generate) Call(schema string) error {
 
 	source, ok := g.config.Connections[g.config.Source]
 	if !ok {
-		g.errorColor.Printf("Config for '%s' not found", g.boldFont.Sprint(g.config.Source))
+		progress.Stop()
 
-		return nil
+		return Errorf(ERROR_NOT_FOUND, "config for '%s' not found", g.config.Source)
 	}
 
 	schemaConfig, ok := source.Schemas[schema]
 	if !ok {
-		g.errorColor.Printf("Schema '%s' not found\n", g.boldFont.Sprint(schema))
+		progress.Stop()
 
-		return nil
+		return Errorf(ERROR_NOT_FOUND, "schema '%s' not found", schema)
 	}
 
-	os.MkdirAll(fmt.Sprintf("%s/%s", g.config.Folder, schema), 0777)
+	schemaConfig["excludes"] = append(schemaConfig["excludes"], db.HISTORY_TABLE)
+
+	os.MkdirAll(fmt.Sprintf("%s/%s/%s", g.config.Folder, schema, REPEATABLE_FOLDER), 0777)
 
 	version := time.Now().Unix()
 
+	var failure error
+	mutex := iSync.Mutex{}
+	fail := func(err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if failure == nil {
+			failure = err
+		}
+	}
+
+	progress.Stop()
+	progress.Suffix = fmt.Sprintf(" Resolving dependencies on schema %s...", g.successColor.Sprint(schema))
+	progress.Start()
+
+	objects, cycles, err := db.NewDependency(g.connection).Sort(schema, schemaConfig["excludes"]...)
+	if err != nil {
+		progress.Stop()
+
+		return Wrap(ERROR_CONNECTION, err)
+	}
+
+	if len(cycles) > 0 {
+		progress.Stop()
+
+		return Errorf(ERROR_INVALID, "dependency cycle detected between %s, break the cycle or exclude one of the objects", strings.Join(cycles, ", "))
+	}
+
+	var tables int64
+	var position int64
+	versions := map[string]int64{}
+	positions := map[string]int64{}
+	for _, o := range objects {
+		versions[o.Key()] = version
+		positions[o.Key()] = position
+		position += int64(o.Total)
+		version += int64(o.Total)
+		if o.Kind == db.OBJECT_TABLE {
+			version += int64(o.Total)
+			tables += int64(o.Total)
+		}
+	}
+
+	fkVersion := version
+	insertVersion := fkVersion + tables
+	version = insertVersion + tables
+
+	counters := map[string]int64{}
+	next := func(kind string, name string) int64 {
+		key := db.ObjectKey(kind, name)
+		base, ok := versions[key]
+		if !ok {
+			version++
+
+			return version - 1
+		}
+
+		v := base + counters[key]
+		counters[key]++
+
+		return v
+	}
+
+	orders := map[string]int64{}
+	order := func(kind string, name string) int64 {
+		key := db.ObjectKey(kind, name)
+		base, ok := positions[key]
+		if !ok {
+			position++
+
+			return position - 1
+		}
+
+		o := base + orders[key]
+		orders[key]++
+
+		return o
+	}
+
 	progress.Stop()
 	progress.Suffix = fmt.Sprintf(" Processing enums on schema %s...", g.successColor.Sprint(schema))
 	progress.Start()
 
+	wg := iSync.WaitGroup{}
+
 	udts := db.NewEnum(g.connection).GenerateDdl(schema)
 	for s := range udts {
+		wg.Add(1)
 		go func(version int64, schema string, ddl db.Migration) {
+			defer wg.Done()
+
 			err := os.WriteFile(fmt.Sprintf("%s/%s/%d_enum_%s.up.sql", g.config.Folder, schema, version, ddl.Name), []byte(ddl.UpScript), 0777)
 			if err != nil {
-				progress.Stop()
-
-				g.errorColor.Println(err.Error())
+				fail(err)
 
 				return
 			}
 
 			err = os.WriteFile(fmt.Sprintf("%s/%s/%d_enum_%s.down.sql", g.config.Folder, schema, version, ddl.Name), []byte(ddl.DownScript), 0777)
 			if err != nil {
-				progress.Stop()
-
-				g.errorColor.Println(err.Error())
-
-				return
+				fail(err)
 			}
-		}(version, schema, s)
-
-		version++
+		}(next(db.OBJECT_ENUM, s.Name), schema, s)
 	}
 
 	nWorker := 5
 	schemaTool := db.NewSchema(g.connection)
 	cTable := schemaTool.ListTable(nWorker, schema, schemaConfig["excludes"]...)
 
-	ddlTool := db.NewTable(g.config.PgDump, source, g.connection)
+	command := ""
+	if g.config.Generator == config.GENERATOR_PG_DUMP {
+		command = g.config.PgDump
+	}
+
+	ddlTool := db.NewTable(command, source, g.connection)
 	cDdl := make(chan db.Ddl, nWorker)
 	cInsert := make(chan db.Ddl, nWorker)
 	tTable := schemaTool.CountTable(schema, len(schemaConfig["excludes"]))
 
-	go func(version int64, schema string, tTable int, cDdl chan<- db.Ddl, cTable <-chan string) {
+	go func(versions map[string]int64, schema string, tTable int, cDdl chan<- db.Ddl, cTable <-chan string) {
 		cMigration := make(chan migration, nWorker)
 		wg := iSync.WaitGroup{}
 
@@ -163,6 +248,17 @@ func (g generate) Call(schema string) error {
 			progress.Suffix = fmt.Sprintf(" Processing table %s (%d/%d) on schema %s...", g.successColor.Sprint(tableName), count, tTable, g.successColor.Sprint(schema))
 			progress.Start()
 
+			version, ok := versions[db.ObjectKey(db.OBJECT_TABLE, tableName)]
+			if !ok {
+				progress.Stop()
+
+				g.errorColor.Printf("Table '%s' not found in dependency graph\n", g.boldFont.Sprint(tableName))
+
+				count++
+
+				continue
+			}
+
 			schemaOnly := true
 			for _, d := range schemaConfig["with_data"] {
 				if d == tableName {
@@ -182,17 +278,16 @@ func (g generate) Call(schema string) error {
 				schema:     schema,
 				schemaOnly: schemaOnly,
 				table:      tableName,
+				fail:       fail,
 			}
 
-			version += 2
 			count++
 		}
 		wg.Wait()
 
 		close(cDdl)
-	}(version, schema, tTable, cDdl, cTable)
+	}(versions, schema, tTable, cDdl, cTable)
 
-	version = version + int64(tTable*2)
 	go func(version int64, schema string, cDdl <-chan db.Ddl, cInsert chan<- db.Ddl) {
 		for ddl := range cDdl {
 			cInsert <- ddl
@@ -203,18 +298,14 @@ func (g generate) Call(schema string) error {
 
 			err := os.WriteFile(fmt.Sprintf("%s/%s/%d_foreign_key_%s.up.sql", g.config.Folder, schema, version, ddl.Name), []byte(ddl.ForeignKey.UpScript), 0777)
 			if err != nil {
-				progress.Stop()
-
-				g.errorColor.Println(err.Error())
+				fail(err)
 
 				continue
 			}
 
 			err = os.WriteFile(fmt.Sprintf("%s/%s/%d_foreign_key_%s.down.sql", g.config.Folder, schema, version, ddl.Name), []byte(ddl.ForeignKey.DownScript), 0777)
 			if err != nil {
-				progress.Stop()
-
-				g.errorColor.Println(err.Error())
+				fail(err)
 
 				continue
 			}
@@ -223,150 +314,134 @@ func (g generate) Call(schema string) error {
 		}
 
 		close(cInsert)
-	}(version, schema, cDdl, cInsert)
+	}(fkVersion, schema, cDdl, cInsert)
 
-	version = version + int64(tTable*2)
 	for ddl := range cInsert {
 		if ddl.Insert.UpScript == "" {
 			continue
 		}
 
-		err := os.WriteFile(fmt.Sprintf("%s/%s/%d_insert_%s.up.sql", g.config.Folder, schema, version, ddl.Name), []byte(ddl.Insert.UpScript), 0777)
+		err := os.WriteFile(fmt.Sprintf("%s/%s/%d_insert_%s.up.sql", g.config.Folder, schema, insertVersion, ddl.Name), []byte(ddl.Insert.UpScript), 0777)
 		if err != nil {
-			progress.Stop()
-
-			g.errorColor.Println(err.Error())
+			fail(err)
 
 			continue
 		}
 
-		err = os.WriteFile(fmt.Sprintf("%s/%s/%d_insert_%s.down.sql", g.config.Folder, schema, version, ddl.Name), []byte(ddl.Insert.DownScript), 0777)
+		err = os.WriteFile(fmt.Sprintf("%s/%s/%d_insert_%s.down.sql", g.config.Folder, schema, insertVersion, ddl.Name), []byte(ddl.Insert.DownScript), 0777)
 		if err != nil {
-			progress.Stop()
-
-			g.errorColor.Println(err.Error())
+			fail(err)
 
 			continue
 		}
 
-		version++
+		insertVersion++
 	}
 
 	progress.Stop()
 	progress.Suffix = fmt.Sprintf(" Processing functions on schema %s...", g.successColor.Sprint(schema))
 	progress.Start()
 
-	wg := iSync.WaitGroup{}
-
 	functions := db.NewFunction(g.connection).GenerateDdl(schema)
 	for s := range functions {
 		wg.Add(1)
-		go func(version int64, schema string, ddl db.Migration) {
-			err := os.WriteFile(fmt.Sprintf("%s/%s/%d_function_%s.up.sql", g.config.Folder, schema, version, ddl.Name), []byte(ddl.UpScript), 0777)
-			if err != nil {
-				progress.Stop()
-
-				wg.Done()
-
-				g.errorColor.Println(err.Error())
+		go func(order int64, after int64, schema string, ddl db.Migration) {
+			defer wg.Done()
 
-				return
-			}
-
-			err = os.WriteFile(fmt.Sprintf("%s/%s/%d_function_%s.down.sql", g.config.Folder, schema, version, ddl.Name), []byte(ddl.DownScript), 0777)
+			err := g.repeatable(schema, order, after, db.OBJECT_FUNCTION, ddl.Name, ddl.UpScript)
 			if err != nil {
-				progress.Stop()
-
-				wg.Done()
-
-				g.errorColor.Println(err.Error())
-
-				return
+				fail(err)
 			}
-
-			wg.Done()
-		}(version, schema, s)
-
-		version++
+		}(order(db.OBJECT_FUNCTION, s.Name), next(db.OBJECT_FUNCTION, s.Name), schema, s)
 	}
 
 	progress.Stop()
 	progress.Suffix = fmt.Sprintf(" Processing views on schema %s...", g.successColor.Sprint(schema))
 	progress.Start()
 
-	views := db.NewView(g.connection).GenerateDdl(schema)
-	for s := range views {
-		wg.Add(1)
-		go func(version int64, schema string, ddl db.Migration) {
-			err := os.WriteFile(fmt.Sprintf("%s/%s/%d_view_%s.up.sql", g.config.Folder, schema, version, ddl.Name), []byte(ddl.UpScript), 0777)
-			if err != nil {
-				progress.Stop()
-
-				wg.Done()
+	definitions := map[string]string{}
+	views := []db.Migration{}
+	for s := range db.NewView(g.connection).GenerateDdl(schema) {
+		views = append(views, s)
+		definitions[db.ObjectKey(db.OBJECT_VIEW, s.Name)] = s.UpScript
+	}
 
-				g.errorColor.Println(err.Error())
+	mViews := []db.Migration{}
+	for s := range db.NewMaterializedView(g.connection).GenerateDdl(schema) {
+		mViews = append(mViews, s)
+		definitions[db.ObjectKey(db.OBJECT_MATERIALIZED_VIEW, s.Name)] = s.UpScript
+	}
 
-				return
-			}
+	for _, s := range views {
+		wg.Add(1)
+		go func(order int64, after int64, schema string, ddl db.Migration) {
+			defer wg.Done()
 
-			err = os.WriteFile(fmt.Sprintf("%s/%s/%d_view_%s.down.sql", g.config.Folder, schema, version, ddl.Name), []byte(ddl.DownScript), 0777)
+			err := g.repeatable(schema, order, after, db.OBJECT_VIEW, ddl.Name, ddl.UpScript)
 			if err != nil {
-				progress.Stop()
-
-				wg.Done()
-
-				g.errorColor.Println(err.Error())
-
-				return
+				fail(err)
 			}
-
-			wg.Done()
-		}(version, schema, s)
-
-		version++
+		}(order(db.OBJECT_VIEW, s.Name), next(db.OBJECT_VIEW, s.Name), schema, s)
 	}
 
 	progress.Stop()
 	progress.Suffix = fmt.Sprintf(" Processing materialized views on schema %s...", g.successColor.Sprint(schema))
 	progress.Start()
 
-	mViews := db.NewMaterializedView(g.connection).GenerateDdl(schema)
-	for s := range mViews {
-		wg.Add(1)
-		go func(version int64, schema string, ddl db.Migration, wg *iSync.WaitGroup) {
-			err := os.WriteFile(fmt.Sprintf("%s/%s/%d_materialized_view_%s.up.sql", g.config.Folder, schema, version, ddl.Name), []byte(ddl.UpScript), 0777)
-			if err != nil {
-				progress.Stop()
-
-				wg.Done()
+	for _, s := range mViews {
+		script := []string{fmt.Sprintf(db.SECURE_DROP_MATERIALIZED_VIEW_CASCADE, s.Name), s.UpScript}
+		script = append(script, g.dependents(objects, definitions, db.ObjectKey(db.OBJECT_MATERIALIZED_VIEW, s.Name))...)
 
-				g.errorColor.Println(err.Error())
+		wg.Add(1)
+		go func(order int64, after int64, schema string, name string, script string) {
+			defer wg.Done()
 
-				return
+			err := g.repeatable(schema, order, after, db.OBJECT_MATERIALIZED_VIEW, name, script)
+			if err != nil {
+				fail(err)
 			}
+		}(order(db.OBJECT_MATERIALIZED_VIEW, s.Name), next(db.OBJECT_MATERIALIZED_VIEW, s.Name), schema, s.Name, strings.Join(script, "\n"))
+	}
+	wg.Wait()
 
-			err = os.WriteFile(fmt.Sprintf("%s/%s/%d_materialized_view_%s.down.sql", g.config.Folder, schema, version, ddl.Name), []byte(ddl.DownScript), 0777)
-			if err != nil {
-				progress.Stop()
+	progress.Stop()
+
+	if failure != nil {
+		return Wrap(ERROR_MIGRATION, failure)
+	}
 
-				wg.Done()
+	g.successColor.Printf("Migration generation on schema %s run successfully\n", g.boldFont.Sprint(schema))
 
-				g.errorColor.Println(err.Error())
+	return nil
+}
 
-				return
-			}
+func (g generate) repeatable(schema string, order int64, after int64, kind string, name string, script string) error {
+	script = fmt.Sprintf("-- %s%s=%d\n%s", config.ANNOTATION_PREFIX, config.ANNOTATION_AFTER, after, script)
 
-			wg.Done()
-		}(version, schema, s, &wg)
+	return os.WriteFile(fmt.Sprintf("%s/%s/%s/R__%06d_%s_%s.sql", g.config.Folder, schema, REPEATABLE_FOLDER, order, kind, name), []byte(script), 0777)
+}
 
-		version++
-	}
+func (g generate) dependents(objects []db.Object, definitions map[string]string, key string) []string {
+	scripts := []string{}
+	dropped := map[string]bool{key: true}
+	for _, o := range objects {
+		if !dropped[o.Key()] {
+			continue
+		}
 
-	wg.Wait()
+		definition, ok := definitions[o.Key()]
+		if !ok {
+			continue
+		}
 
-	progress.Stop()
+		if o.Key() != key {
+			scripts = append(scripts, definition)
+		}
 
-	g.successColor.Printf("Migration generation on schema %s run successfully\n", g.boldFont.Sprint(schema))
+		for _, d := range o.Dependents {
+			dropped[d] = true
+		}
+	}
 
-	return nil
+	return scripts
 }
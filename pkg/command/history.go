@@ -0,0 +1,47 @@
+package command
+
+import (
+	"kmt/pkg/config"
+	"kmt/pkg/db"
+
+	"github.com/fatih/color"
+)
+
+type history struct {
+	config       config.Migration
+	boldFont     *color.Color
+	errorColor   *color.Color
+	successColor *color.Color
+}
+
+func NewHistory(config config.Migration) history {
+	return history{
+		config:       config,
+		boldFont:     color.New(color.Bold),
+		errorColor:   color.New(color.FgRed),
+		successColor: color.New(color.FgGreen),
+	}
+}
+
+func (h history) Call(source string, schema string) ([]db.History, error) {
+	dbConfig, ok := h.config.Connections[source]
+	if !ok {
+		return nil, Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", source)
+	}
+
+	_, ok = dbConfig.Schemas[schema]
+	if !ok {
+		return nil, Errorf(ERROR_NOT_FOUND, "schema '%s' not found on %s", schema, source)
+	}
+
+	conn, err := config.NewConnection(dbConfig)
+	if err != nil {
+		return nil, Wrap(ERROR_CONNECTION, err)
+	}
+
+	defer conn.Close()
+
+	histories, err := db.NewHistory(conn, schema).List()
+
+	return histories, Wrap(ERROR_CONNECTION, err)
+}
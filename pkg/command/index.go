@@ -0,0 +1,144 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type index struct {
+	schema   string
+	versions []uint
+	files    map[uint]map[string]string
+	problems []string
+}
+
+func NewIndex(folder string, schema string) (index, error) {
+	i, err := scan(folder, schema)
+	if err != nil {
+		return i, err
+	}
+
+	if len(i.problems) > 0 {
+		return i, Errorf(ERROR_INVALID, "migration folder of schema %s is invalid:\n  - %s", schema, strings.Join(i.problems, "\n  - "))
+	}
+
+	return i, nil
+}
+
+func scan(folder string, schema string) (index, error) {
+	i := index{schema: schema, versions: []uint{}, files: map[uint]map[string]string{}, problems: []string{}}
+
+	entries, err := os.ReadDir(fmt.Sprintf("%s/%s", folder, schema))
+	if err != nil {
+		return i, Wrap(ERROR_NOT_FOUND, err)
+	}
+
+	names := map[uint]string{}
+	for _, e := range entries {
+		if e.IsDir() {
+			if e.Name() != REPEATABLE_FOLDER {
+				i.problems = append(i.problems, fmt.Sprintf("unexpected folder %s", e.Name()))
+			}
+
+			continue
+		}
+
+		m := migrationFile.FindStringSubmatch(e.Name())
+		if m == nil {
+			i.problems = append(i.problems, fmt.Sprintf("unexpected file %s, migration files are named <version>_<name>.up.sql and <version>_<name>.down.sql", e.Name()))
+
+			continue
+		}
+
+		v, err := strconv.ParseUint(m[1], 10, 0)
+		if err != nil {
+			i.problems = append(i.problems, fmt.Sprintf("invalid version in %s", e.Name()))
+
+			continue
+		}
+
+		version := uint(v)
+		_, ok := i.files[version]
+		if !ok {
+			i.files[version] = map[string]string{}
+			i.versions = append(i.versions, version)
+			names[version] = m[2]
+		}
+
+		if names[version] != m[2] {
+			i.problems = append(i.problems, fmt.Sprintf("version %d is shared by %s and %s", version, names[version], m[2]))
+		}
+
+		existing, ok := i.files[version][m[3]]
+		if ok {
+			i.problems = append(i.problems, fmt.Sprintf("version %d has more than one %s file: %s, %s", version, m[3], existing, e.Name()))
+
+			continue
+		}
+
+		i.files[version][m[3]] = e.Name()
+	}
+
+	sort.Slice(i.versions, func(x, y int) bool {
+		return i.versions[x] < i.versions[y]
+	})
+
+	for _, v := range i.versions {
+		for _, direction := range []string{DIRECTION_UP, DIRECTION_DOWN} {
+			_, ok := i.files[v][direction]
+			if !ok {
+				i.problems = append(i.problems, fmt.Sprintf("version %d has no %s file", v, direction))
+			}
+		}
+	}
+
+	return i, nil
+}
+
+func (i index) Latest() uint {
+	if len(i.versions) == 0 {
+		return 0
+	}
+
+	return i.versions[len(i.versions)-1]
+}
+
+func (i index) has(version uint) bool {
+	_, ok := i.files[version]
+
+	return ok
+}
+
+func (i index) between(from uint, to uint) int {
+	number := 0
+	for _, v := range i.versions {
+		if v > from && v <= to {
+			number++
+		}
+	}
+
+	return number
+}
+
+func schemaFolders(folder string, schema string) ([]string, error) {
+	if schema != "" {
+		return []string{schema}, nil
+	}
+
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		return nil, Wrap(ERROR_NOT_FOUND, err)
+	}
+
+	schemas := []string{}
+	for _, e := range entries {
+		if e.IsDir() {
+			schemas = append(schemas, e.Name())
+		}
+	}
+
+	return schemas, nil
+}
@@ -0,0 +1,383 @@
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"kmt/pkg/config"
+	"kmt/pkg/db"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	initialize struct {
+		reader       *bufio.Reader
+		boldFont     *color.Color
+		errorColor   *color.Color
+		successColor *color.Color
+	}
+
+	namedConnection struct {
+		name       string
+		connection config.Connection
+	}
+)
+
+func NewInit() initialize {
+	return initialize{
+		reader:       bufio.NewReader(os.Stdin),
+		boldFont:     color.New(color.Bold),
+		errorColor:   color.New(color.FgRed),
+		successColor: color.New(color.FgGreen),
+	}
+}
+
+func (i initialize) Call(path string, folder string, connections []string, schemas []string, generate bool) error {
+	_, err := os.Stat(path)
+	if err == nil {
+		return Errorf(ERROR_INVALID, "%s already exists", path)
+	}
+
+	interactive := len(connections) == 0
+
+	lists := []namedConnection{}
+	for _, c := range connections {
+		named, err := parseConnection(c)
+		if err != nil {
+			return err
+		}
+
+		err = dial(named.connection)
+		if err != nil {
+			return Errorf(ERROR_CONNECTION, "connection '%s' error %s", named.name, err.Error())
+		}
+
+		i.successColor.Printf("Connection %s is ok\n", i.boldFont.Sprint(named.name))
+
+		lists = append(lists, named)
+	}
+
+	for interactive {
+		named, err := i.askConnection(len(lists) == 0)
+		if err != nil {
+			return err
+		}
+
+		err = dial(named.connection)
+		if err != nil {
+			i.errorColor.Printf("Connection '%s' error %s\n", named.name, err.Error())
+
+			continue
+		}
+
+		i.successColor.Printf("Connection %s is ok\n", i.boldFont.Sprint(named.name))
+
+		lists = append(lists, named)
+
+		interactive, err = i.confirm("Add another connection?", false)
+		if err != nil {
+			return err
+		}
+	}
+
+	source := lists[0].connection
+	source.Password, _ = config.Resolve(source.Password)
+
+	available, err := listSchemas(source)
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
+	}
+
+	fmt.Printf("Schemas on %s: %s\n", i.boldFont.Sprint(lists[0].name), strings.Join(available, ", "))
+
+	if len(schemas) == 0 && len(connections) == 0 {
+		answer, err := i.ask("Schemas to manage (comma separated)", strings.Join(available, ","))
+		if err != nil {
+			return err
+		}
+
+		for _, s := range strings.Split(answer, ",") {
+			if strings.TrimSpace(s) != "" {
+				schemas = append(schemas, strings.TrimSpace(s))
+			}
+		}
+	}
+
+	if len(schemas) == 0 {
+		schemas = available
+	}
+
+	for _, s := range schemas {
+		found := false
+		for _, a := range available {
+			if a == s {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return Errorf(ERROR_NOT_FOUND, "schema '%s' not found on %s", s, lists[0].name)
+		}
+	}
+
+	err = os.WriteFile(path, []byte(kmtfile(folder, lists, schemas)), 0644)
+	if err != nil {
+		return err
+	}
+
+	i.successColor.Printf("Config written to %s\n", i.boldFont.Sprint(path))
+
+	cfg, err := config.Parse(path, "")
+	if err != nil {
+		return Wrap(ERROR_INVALID, err)
+	}
+
+	for _, s := range schemas {
+		os.MkdirAll(fmt.Sprintf("%s/%s", cfg.Migration.Folder, s), 0777)
+	}
+
+	if len(connections) == 0 {
+		generate, err = i.confirm(fmt.Sprintf("Generate migrations from %s now?", lists[0].name), false)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !generate {
+		return nil
+	}
+
+	conn, err := config.NewConnection(cfg.Migration.Connections[cfg.Migration.Source])
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
+	}
+
+	defer conn.Close()
+
+	cmd := NewGenerate(cfg.Migration, conn)
+	for _, s := range schemas {
+		err = cmd.Call(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (i initialize) askConnection(first bool) (namedConnection, error) {
+	name := "source"
+	if !first {
+		name = ""
+	}
+
+	named := namedConnection{}
+
+	var err error
+	for named.name == "" && err == nil {
+		named.name, err = i.ask("Connection name", name)
+	}
+
+	if err == nil {
+		named.connection.Host, err = i.ask("Host", "localhost")
+	}
+
+	for named.connection.Port == 0 && err == nil {
+		var port string
+		port, err = i.ask("Port", "5432")
+
+		n, pErr := strconv.Atoi(port)
+		if pErr == nil && n > 0 && n < 65536 {
+			named.connection.Port = n
+		}
+	}
+
+	for named.connection.Name == "" && err == nil {
+		named.connection.Name, err = i.ask("Database", "")
+	}
+
+	if err == nil {
+		named.connection.User, err = i.ask("User", "postgres")
+	}
+
+	if err == nil {
+		named.connection.Password, err = i.ask("Password (or ${ENV_VAR})", "")
+	}
+
+	if err == nil {
+		named.connection.SslMode, err = i.ask("SSL mode", config.SSL_MODE_DISABLE)
+	}
+
+	return named, err
+}
+
+func (i initialize) ask(question string, fallback string) (string, error) {
+	if fallback != "" {
+		fmt.Printf("%s [%s]: ", question, fallback)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+
+	answer, err := i.reader.ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Println()
+
+		return "", Errorf(ERROR_INVALID, "no answer for '%s'", question)
+	}
+
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return fallback, nil
+	}
+
+	return answer, nil
+}
+
+func (i initialize) confirm(question string, fallback bool) (bool, error) {
+	options := "y/N"
+	if fallback {
+		options = "Y/n"
+	}
+
+	answer, err := i.ask(fmt.Sprintf("%s [%s]", question, options), "")
+	if err != nil {
+		return fallback, err
+	}
+
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true, nil
+	case "n", "no":
+		return false, nil
+	}
+
+	return fallback, nil
+}
+
+func parseConnection(value string) (namedConnection, error) {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return namedConnection{}, Errorf(ERROR_INVALID, "connection '%s' must be <name>=postgres://<user>:<password>@<host>:<port>/<database>", value)
+	}
+
+	u, err := url.Parse(parts[1])
+	if err != nil {
+		return namedConnection{}, Errorf(ERROR_INVALID, "connection '%s' has invalid url: %w", parts[0], err)
+	}
+
+	named := namedConnection{name: parts[0]}
+	named.connection.Host = u.Hostname()
+	named.connection.Port = 5432
+	named.connection.Name = strings.TrimPrefix(u.Path, "/")
+	named.connection.SslMode = u.Query().Get("sslmode")
+
+	if u.Port() != "" {
+		named.connection.Port, err = strconv.Atoi(u.Port())
+		if err != nil {
+			return namedConnection{}, Errorf(ERROR_INVALID, "connection '%s' has invalid port", parts[0])
+		}
+	}
+
+	if u.User != nil {
+		named.connection.User = u.User.Username()
+		named.connection.Password, _ = u.User.Password()
+	}
+
+	return named, nil
+}
+
+func dial(connection config.Connection) error {
+	password, err := config.Resolve(connection.Password)
+	if err != nil {
+		return err
+	}
+
+	connection.Password = password
+
+	return ping(connection)
+}
+
+func listSchemas(connection config.Connection) ([]string, error) {
+	conn, err := config.NewConnection(connection)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	rows, err := conn.Query(db.QUERY_LIST_SCHEMA)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	schemas := []string{}
+	for rows.Next() {
+		var schema string
+		err = rows.Scan(&schema)
+		if err != nil {
+			return nil, err
+		}
+
+		schemas = append(schemas, schema)
+	}
+
+	return schemas, rows.Err()
+}
+
+func kmtfile(folder string, connections []namedConnection, schemas []string) string {
+	var file strings.Builder
+
+	names := []string{}
+	for _, c := range connections {
+		names = append(names, c.name)
+	}
+
+	file.WriteString("version: 1.0\n\n")
+	file.WriteString("migration:\n")
+	file.WriteString(fmt.Sprintf("  generator: %s\n", config.GENERATOR_CATALOG))
+	file.WriteString(fmt.Sprintf("  folder: %s\n", scalar(folder)))
+	file.WriteString(fmt.Sprintf("  source: %s\n", scalar(names[0])))
+	file.WriteString("  clusters:\n")
+	file.WriteString(fmt.Sprintf("    all: [%s]\n", strings.Join(names, ", ")))
+	file.WriteString("  connections:\n")
+
+	for _, c := range connections {
+		file.WriteString(fmt.Sprintf("    %s:\n", scalar(c.name)))
+		file.WriteString(fmt.Sprintf("      host: %s\n", scalar(c.connection.Host)))
+		file.WriteString(fmt.Sprintf("      port: %d\n", c.connection.Port))
+		file.WriteString(fmt.Sprintf("      name: %s\n", scalar(c.connection.Name)))
+		file.WriteString(fmt.Sprintf("      user: %s\n", scalar(c.connection.User)))
+		file.WriteString(fmt.Sprintf("      password: %s\n", scalar(c.connection.Password)))
+
+		if c.connection.SslMode != "" {
+			file.WriteString(fmt.Sprintf("      sslmode: %s\n", scalar(c.connection.SslMode)))
+		}
+
+		file.WriteString("      schemas:\n")
+		for _, s := range schemas {
+			file.WriteString(fmt.Sprintf("        %s:\n", scalar(s)))
+			file.WriteString("          excludes: []\n")
+			file.WriteString("          with_data: []\n")
+		}
+	}
+
+	return file.String()
+}
+
+func scalar(value string) string {
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,269 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"kmt/pkg/config"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type (
+	lint struct {
+		config       config.Migration
+		boldFont     *color.Color
+		errorColor   *color.Color
+		successColor *color.Color
+	}
+
+	finding struct {
+		Schema   string `json:"schema"`
+		File     string `json:"file"`
+		Line     int    `json:"line"`
+		Rule     string `json:"rule"`
+		Severity string `json:"severity"`
+		Message  string `json:"message"`
+	}
+)
+
+const (
+	LINT_FORMAT_TEXT = "text"
+	LINT_FORMAT_JSON = "json"
+)
+
+var (
+	lintDefaults = map[string]string{
+		config.LINT_INDEX_NOT_CONCURRENT:     config.LINT_ERROR,
+		config.LINT_NOT_NULL_WITHOUT_DEFAULT: config.LINT_ERROR,
+		config.LINT_COLUMN_TYPE_CHANGE:       config.LINT_WARNING,
+		config.LINT_DROP_WITHOUT_DOWN:        config.LINT_ERROR,
+		config.LINT_RENAME:                   config.LINT_WARNING,
+	}
+
+	createTable   = regexp.MustCompile(`(?is)^CREATE\s+(?:(?:GLOBAL|LOCAL)\s+)?(?:UNLOGGED\s+|TEMP\s+|TEMPORARY\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?([^\s(]+)`)
+	createIndex   = regexp.MustCompile(`(?is)^CREATE\s+(?:UNIQUE\s+)?INDEX\b(.*?)\bON\s+(?:ONLY\s+)?([^\s(]+)`)
+	alterTable    = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?(\S+)\s+(.*)$`)
+	dropTable     = regexp.MustCompile(`(?is)^DROP\s+TABLE\s+(?:IF\s+EXISTS\s+)?(.+?)(?:\s+(?:CASCADE|RESTRICT))?$`)
+	renameObject  = regexp.MustCompile(`(?is)^ALTER\s+.*\bRENAME\s+(?:TO|COLUMN|CONSTRAINT|ATTRIBUTE|VALUE)\b`)
+	addColumn     = regexp.MustCompile(`(?is)^ADD\s+(?:COLUMN\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(\S+)\s+(.*)$`)
+	addConstraint = regexp.MustCompile(`(?is)^ADD\s+(?:CONSTRAINT|PRIMARY|UNIQUE|FOREIGN|CHECK|EXCLUDE)\b`)
+	alterType     = regexp.MustCompile(`(?is)^ALTER\s+(?:COLUMN\s+)?(\S+)\s+(?:SET\s+DATA\s+)?TYPE\b`)
+	dropColumn    = regexp.MustCompile(`(?is)^DROP\s+(?:COLUMN\s+)?(?:IF\s+EXISTS\s+)?(\S+)`)
+	dropOther     = regexp.MustCompile(`(?is)^DROP\s+CONSTRAINT\b`)
+	notNull       = regexp.MustCompile(`(?is)\bNOT\s+NULL\b`)
+	hasDefault    = regexp.MustCompile(`(?is)\b(?:DEFAULT|GENERATED)\b`)
+	concurrently  = regexp.MustCompile(`(?is)\bCONCURRENTLY\b`)
+)
+
+func NewLint(config config.Migration) lint {
+	return lint{
+		config:       config,
+		boldFont:     color.New(color.Bold),
+		errorColor:   color.New(color.FgRed),
+		successColor: color.New(color.FgGreen),
+	}
+}
+
+func (l lint) Call(schema string, format string) error {
+	if format != LINT_FORMAT_TEXT && format != LINT_FORMAT_JSON {
+		return Errorf(ERROR_INVALID, "unknown format '%s', must be %s or %s", format, LINT_FORMAT_TEXT, LINT_FORMAT_JSON)
+	}
+
+	schemas, err := schemaFolders(l.config.Folder, schema)
+	if err != nil {
+		return err
+	}
+
+	findings := []finding{}
+	for _, s := range schemas {
+		result, err := l.schema(s)
+		if err != nil {
+			return err
+		}
+
+		findings = append(findings, result...)
+	}
+
+	errors := 0
+	for _, f := range findings {
+		if f.Severity == config.LINT_ERROR {
+			errors++
+		}
+	}
+
+	if format == LINT_FORMAT_JSON {
+		out, err := json.MarshalIndent(findings, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(out))
+	} else {
+		l.print(findings)
+	}
+
+	if errors > 0 {
+		return Errorf(ERROR_LINT, "%d lint error(s) found", errors)
+	}
+
+	return nil
+}
+
+func (l lint) schema(schema string) ([]finding, error) {
+	i, err := scan(l.config.Folder, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	findings := []finding{}
+	for _, v := range i.versions {
+		file, ok := i.files[v][DIRECTION_UP]
+		if !ok {
+			continue
+		}
+
+		content, err := os.ReadFile(fmt.Sprintf("%s/%s/%s", l.config.Folder, schema, file))
+		if err != nil {
+			return nil, err
+		}
+
+		downContent, _ := os.ReadFile(fmt.Sprintf("%s/%s/%s", l.config.Folder, schema, i.files[v][DIRECTION_DOWN]))
+		hasDown := len(statements(string(downContent))) > 0
+
+		for _, f := range l.check(statements(string(content)), hasDown) {
+			f.Schema = schema
+			f.File = file
+			f.Severity = l.severity(f.Rule)
+			if f.Severity == config.LINT_OFF {
+				continue
+			}
+
+			findings = append(findings, f)
+		}
+	}
+
+	return findings, nil
+}
+
+func (l lint) check(statements []statement, hasDown bool) []finding {
+	created := map[string]bool{}
+	for _, s := range statements {
+		m := createTable.FindStringSubmatch(s.text)
+		if m != nil {
+			created[unqualified(m[1])] = true
+		}
+	}
+
+	findings := []finding{}
+	for _, s := range statements {
+		m := createIndex.FindStringSubmatch(s.text)
+		if m != nil && !concurrently.MatchString(m[1]) && !created[unqualified(m[2])] {
+			findings = append(findings, finding{Line: s.line, Rule: config.LINT_INDEX_NOT_CONCURRENT, Message: fmt.Sprintf("index on existing table %s is created without CONCURRENTLY and blocks writes", m[2])})
+		}
+
+		m = dropTable.FindStringSubmatch(s.text)
+		if m != nil && !hasDown {
+			findings = append(findings, finding{Line: s.line, Rule: config.LINT_DROP_WITHOUT_DOWN, Message: fmt.Sprintf("table %s is dropped without a down script", m[1])})
+		}
+
+		m = alterTable.FindStringSubmatch(s.text)
+		if m == nil {
+			if renameObject.MatchString(s.text) {
+				findings = append(findings, finding{Line: s.line, Rule: config.LINT_RENAME, Message: "rename breaks code still using the old name"})
+			}
+
+			continue
+		}
+
+		table := m[1]
+		for _, action := range actions(m[2]) {
+			switch {
+			case strings.HasPrefix(strings.ToUpper(action), "RENAME"):
+				findings = append(findings, finding{Line: s.line, Rule: config.LINT_RENAME, Message: fmt.Sprintf("rename on %s breaks code still using the old name", table)})
+			case addColumn.MatchString(action) && !addConstraint.MatchString(action):
+				c := addColumn.FindStringSubmatch(action)
+				if !created[unqualified(table)] && notNull.MatchString(c[2]) && !hasDefault.MatchString(c[2]) {
+					findings = append(findings, finding{Line: s.line, Rule: config.LINT_NOT_NULL_WITHOUT_DEFAULT, Message: fmt.Sprintf("column %s.%s is added as NOT NULL without a default and fails on existing rows", table, c[1])})
+				}
+			case alterType.MatchString(action):
+				c := alterType.FindStringSubmatch(action)
+				if !created[unqualified(table)] {
+					findings = append(findings, finding{Line: s.line, Rule: config.LINT_COLUMN_TYPE_CHANGE, Message: fmt.Sprintf("type change on %s.%s may rewrite the table under an exclusive lock", table, c[1])})
+				}
+			case dropColumn.MatchString(action) && !dropOther.MatchString(action):
+				c := dropColumn.FindStringSubmatch(action)
+				if !hasDown {
+					findings = append(findings, finding{Line: s.line, Rule: config.LINT_DROP_WITHOUT_DOWN, Message: fmt.Sprintf("column %s.%s is dropped without a down script", table, c[1])})
+				}
+			}
+		}
+	}
+
+	return findings
+}
+
+func (l lint) severity(rule string) string {
+	severity, ok := l.config.Lint[rule]
+	if ok {
+		return severity
+	}
+
+	return lintDefaults[rule]
+}
+
+func (l lint) print(findings []finding) {
+	if len(findings) == 0 {
+		l.successColor.Println("No risky migration found")
+
+		return
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Schema != findings[j].Schema {
+			return findings[i].Schema < findings[j].Schema
+		}
+
+		return findings[i].File < findings[j].File
+	})
+
+	for _, f := range findings {
+		severity := l.boldFont.Sprint(f.Severity)
+		if f.Severity == config.LINT_ERROR {
+			severity = color.New(color.FgRed, color.Bold).Sprint(f.Severity)
+		}
+
+		fmt.Printf("%s/%s:%d: %s [%s] %s\n", f.Schema, f.File, f.Line, severity, f.Rule, f.Message)
+	}
+}
+
+func actions(clause string) []string {
+	result := []string{}
+
+	depth := 0
+	last := 0
+	for i, r := range clause {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				result = append(result, strings.TrimSpace(clause[last:i]))
+				last = i + 1
+			}
+		}
+	}
+
+	return append(result, strings.TrimSpace(clause[last:]))
+}
+
+func unqualified(name string) string {
+	parts := strings.Split(strings.Trim(name, `"`), ".")
+
+	return strings.ToLower(strings.Trim(parts[len(parts)-1], `"`))
+}
@@ -0,0 +1,252 @@
+package command
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"kmt/pkg/config"
+	"kmt/pkg/db"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+type lock struct {
+	config       config.Migration
+	boldFont     *color.Color
+	errorColor   *color.Color
+	successColor *color.Color
+}
+
+func NewLock(config config.Migration) lock {
+	return lock{
+		config:       config,
+		boldFont:     color.New(color.Bold),
+		errorColor:   color.New(color.FgRed),
+		successColor: color.New(color.FgGreen),
+	}
+}
+
+func (l lock) Acquire(target string, schema string) (context.Context, func(), error) {
+	holder, nodes, err := l.nodes(target)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, err := config.NewConnection(l.config.Connections[holder])
+	if err != nil {
+		return nil, nil, Wrap(ERROR_CONNECTION, err)
+	}
+
+	random := make([]byte, 16)
+	rand.Read(random)
+	token := hex.EncodeToString(random)
+
+	osUser, host := identity()
+	owner := fmt.Sprintf("%s@%s (pid %d)", osUser, host, os.Getpid())
+	expiry := time.Duration(l.config.LockExpiry) * time.Second
+	deadline := time.Now().Add(time.Duration(l.config.LockWait) * time.Second)
+
+	locker := db.NewLock(conn)
+	for {
+		current, acquired, err := locker.Acquire(target, schema, nodes, token, owner, expiry)
+		if err != nil {
+			conn.Close()
+
+			return nil, nil, Wrap(ERROR_CONNECTION, err)
+		}
+
+		if acquired {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			conn.Close()
+
+			return nil, nil, Errorf(ERROR_LOCKED, "%s schema %s is locked through %s by %s since %s until %s, use 'kmt lock release %s %s' if the lock is stuck", target, schema, current.Target, current.Owner, current.AcquiredAt.Local().Format(time.RFC3339), current.ExpiresAt.Local().Format(time.RFC3339), current.Target, current.Schema)
+		}
+
+		time.Sleep(config.LOCK_POLL)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := make(chan bool)
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(expiry / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				held, err := locker.Refresh(target, schema, token, expiry)
+				if err == nil && held {
+					continue
+				}
+
+				reason := "the lock expired and was taken by another process"
+				if err != nil {
+					reason = err.Error()
+				}
+
+				l.errorColor.Printf("Lost lock on %s schema %s: %s, stopping\n", l.boldFont.Sprint(target), l.boldFont.Sprint(schema), reason)
+
+				cancel()
+
+				return
+			}
+		}
+	}()
+
+	return ctx, func() {
+		close(stop)
+		<-done
+		cancel()
+
+		err := locker.Release(target, schema, token)
+		if err != nil {
+			l.errorColor.Printf("Unable to release lock on %s schema %s: %s\n", l.boldFont.Sprint(target), l.boldFont.Sprint(schema), err.Error())
+		}
+
+		conn.Close()
+	}, nil
+}
+
+func (l lock) Status(target string, schema string) error {
+	holder, nodes, err := l.nodes(target)
+	if err != nil {
+		return err
+	}
+
+	conn, err := config.NewConnection(l.config.Connections[holder])
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
+	}
+
+	defer conn.Close()
+
+	locks, err := db.NewLock(conn).List()
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"No", "Target", "Schema", "Nodes", "Owner", "Acquired At", "Expires At", "Status"})
+
+	number := 1
+	for _, h := range locks {
+		if schema != "" && h.Schema != schema {
+			continue
+		}
+
+		if h.Target != target && !overlap(h.Nodes, nodes) {
+			continue
+		}
+
+		status := color.New(color.FgRed, color.Bold).Sprint("locked")
+		if h.Expired {
+			status = l.boldFont.Sprint("expired")
+		}
+
+		t.AppendRow(table.Row{number, h.Target, h.Schema, strings.Join(h.Nodes, "\n"), h.Owner, h.AcquiredAt.Local().Format(time.RFC3339), h.ExpiresAt.Local().Format(time.RFC3339), status})
+
+		number++
+	}
+
+	if number == 1 {
+		l.successColor.Println("No lock held")
+
+		return nil
+	}
+
+	t.Render()
+
+	return nil
+}
+
+func (l lock) Release(target string, schema string) error {
+	holder, _, err := l.nodes(target)
+	if err != nil {
+		return err
+	}
+
+	conn, err := config.NewConnection(l.config.Connections[holder])
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
+	}
+
+	defer conn.Close()
+
+	ok, err := db.NewLock(conn).ForceRelease(target, schema)
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
+	}
+
+	if !ok {
+		return Errorf(ERROR_NOT_FOUND, "no lock held on %s schema %s", target, schema)
+	}
+
+	l.successColor.Printf("Lock on %s schema %s released\n", l.boldFont.Sprint(target), l.boldFont.Sprint(schema))
+
+	return nil
+}
+
+func (l lock) nodes(target string) (string, []string, error) {
+	connections, ok := l.config.Clusters[target]
+	if !ok {
+		connections = []string{target}
+	}
+
+	if len(connections) == 0 {
+		return "", nil, Errorf(ERROR_NOT_FOUND, "cluster '%s' has no connection", target)
+	}
+
+	nodes := []string{}
+	seen := map[string]bool{}
+	for _, c := range connections {
+		dbConfig, ok := l.config.Connections[c]
+		if !ok {
+			return "", nil, Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", c)
+		}
+
+		database := dbConfig.Database()
+		if seen[database] {
+			continue
+		}
+
+		seen[database] = true
+		nodes = append(nodes, database)
+	}
+
+	sort.Strings(nodes)
+
+	holder := l.config.Source
+	_, ok = l.config.Connections[holder]
+	if !ok {
+		holder = connections[0]
+	}
+
+	return holder, nodes, nil
+}
+
+func overlap(left []string, right []string) bool {
+	for _, a := range left {
+		for _, b := range right {
+			if a == b {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -3,11 +3,9 @@ package command
 import (
 	"fmt"
 	"kmt/pkg/config"
-	"os"
-	"strconv"
-	"strings"
 
 	"github.com/fatih/color"
+	gomigrate "github.com/golang-migrate/migrate/v4"
 )
 
 type migrate struct {
@@ -28,64 +26,60 @@ func NewMigrate(config config.Migration) migrate {
 
 func (s migrate) Call(source string, schema string, version int) error {
 	if version <= 0 {
-		s.errorColor.Println("Invalid version")
-
-		return nil
+		return Errorf(ERROR_INVALID, "invalid version")
 	}
 
-	files, err := os.ReadDir(fmt.Sprintf("%s/%s", s.config.Folder, schema))
+	i, err := NewIndex(s.config.Folder, schema)
 	if err != nil {
-		s.errorColor.Println(err.Error())
-
-		return nil
-	}
-
-	valid := false
-	for _, file := range files {
-		f := strings.Split(file.Name(), "_")
-		s, _ := strconv.Atoi(f[0])
-		if version == s {
-			valid = true
-
-			break
-		}
+		return err
 	}
 
-	if !valid {
-		s.errorColor.Printf("Migration file for version %s not found\n", s.boldFont.Sprint(version))
-
-		return nil
+	if !i.has(uint(version)) {
+		return Errorf(ERROR_NOT_FOUND, "migration file for version %d not found", version)
 	}
 
 	dbConfig, ok := s.config.Connections[source]
 	if !ok {
-		s.errorColor.Printf("Database connection '%s' not found\n", s.boldFont.Sprint(source))
-
-		return nil
+		return Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", source)
 	}
 
 	_, ok = dbConfig.Schemas[schema]
 	if !ok {
-		s.errorColor.Printf("Schema '%s' not found\n", s.boldFont.Sprint(schema))
+		return Errorf(ERROR_NOT_FOUND, "schema '%s' not found", schema)
+	}
 
-		return nil
+	ctx, release, err := NewLock(s.config).Acquire(source, schema)
+	if err != nil {
+		return err
 	}
 
+	defer release()
+
 	db, err := config.NewConnection(dbConfig)
 	if err != nil {
-		s.errorColor.Println(err.Error())
-
-		return nil
+		return Wrap(ERROR_CONNECTION, err)
 	}
 
-	migrator := config.NewMigrator(db, dbConfig.Name, schema, fmt.Sprintf("%s/%s", s.config.Folder, schema))
-	err = migrator.Migrate(uint(version))
+	migrator, err := config.NewMigrator(db, dbConfig.Name, schema, fmt.Sprintf("%s/%s", s.config.Folder, schema))
 	if err != nil {
-		s.errorColor.Println(err.Error())
+		return Wrap(ERROR_CONNECTION, err)
+	}
+
+	runner := newRunner(s.config.Folder, schema, dbConfig, db, migrator)
+	runner.ctx = ctx
+	runner.prepare = NewBackup(s.config).hook(source, schema)
+
+	err = runner.migrate(uint(version))
+	if err != nil && err == gomigrate.ErrNoChange {
+		s.successColor.Printf("Database %s schema %s is already at version %s\n", s.boldFont.Sprint(source), s.boldFont.Sprint(schema), s.boldFont.Sprint(version))
 
 		return nil
 	}
 
+	if err != nil {
+		return runner.failure(source, err)
+	}
+
 	s.successColor.Printf("Migration on %s schema %s migrate to %s\n", s.boldFont.Sprint(source), s.boldFont.Sprint(schema), s.boldFont.Sprint(version))
 
 	return nil
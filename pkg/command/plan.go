@@ -0,0 +1,488 @@
+package command
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"kmt/pkg/config"
+	"kmt/pkg/db"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type (
+	plan struct {
+		config       config.Migration
+		boldFont     *color.Color
+		errorColor   *color.Color
+		successColor *color.Color
+	}
+
+	header struct {
+		transaction bool
+		after       uint
+		settings    map[string]string
+	}
+
+	migrationStep struct {
+		version   uint
+		target    uint
+		after     uint
+		direction string
+		file      string
+	}
+)
+
+const (
+	DIRECTION_UP         = "up"
+	DIRECTION_DOWN       = "down"
+	DIRECTION_REPEATABLE = "repeatable"
+
+	REPEATABLE_FOLDER = "repeatable"
+)
+
+var (
+	migrationFile  = regexp.MustCompile(`^([0-9]+)_(.*)\.(down|up)\.sql$`)
+	repeatableFile = regexp.MustCompile(`^R__(.+)\.sql$`)
+
+	timeouts = []string{"lock_timeout", "statement_timeout", "idle_in_transaction_session_timeout"}
+)
+
+func NewPlan(config config.Migration) plan {
+	return plan{
+		config:       config,
+		boldFont:     color.New(color.Bold),
+		errorColor:   color.New(color.FgRed),
+		successColor: color.New(color.FgGreen),
+	}
+}
+
+func (p plan) Up(source string, schema string, limit int) error {
+	dbConfig, err := p.connection(source, schema)
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := p.version(dbConfig, schema)
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
+	}
+
+	versions, files, err := migrationFiles(p.config.Folder, schema)
+	if err != nil {
+		return Wrap(ERROR_NOT_FOUND, err)
+	}
+
+	repeatables, err := p.repeatables(dbConfig, schema)
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
+	}
+
+	p.render(source, schema, version, dirty, interleave(upSteps(versions, files, version, limit), repeatables, limit == 0))
+
+	return nil
+}
+
+func (p plan) Down(source string, schema string, limit int) error {
+	dbConfig, err := p.connection(source, schema)
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := p.version(dbConfig, schema)
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
+	}
+
+	versions, files, err := migrationFiles(p.config.Folder, schema)
+	if err != nil {
+		return Wrap(ERROR_NOT_FOUND, err)
+	}
+
+	p.render(source, schema, version, dirty, downSteps(versions, files, version, limit))
+
+	return nil
+}
+
+func (p plan) Run(source string, schema string, step int) error {
+	if step <= 0 {
+		return Errorf(ERROR_INVALID, "invalid step")
+	}
+
+	return p.Up(source, schema, step)
+}
+
+func (p plan) Rollback(source string, schema string, step int) error {
+	if step <= 0 {
+		return Errorf(ERROR_INVALID, "invalid step")
+	}
+
+	return p.Down(source, schema, step)
+}
+
+func (p plan) Migrate(source string, schema string, target uint) error {
+	dbConfig, err := p.connection(source, schema)
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := p.version(dbConfig, schema)
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
+	}
+
+	return p.migrate(source, dbConfig, schema, version, dirty, target)
+}
+
+func (p plan) Sync(cluster string, schema string) error {
+	lists, ok := p.config.Clusters[cluster]
+	if !ok {
+		return Errorf(ERROR_NOT_FOUND, "cluster '%s' isn't defined", cluster)
+	}
+
+	for _, c := range lists {
+		if c == p.config.Source {
+			continue
+		}
+
+		_, ok := p.config.Connections[c]
+		if !ok {
+			return Errorf(ERROR_NOT_FOUND, "connection '%s' isn't defined", c)
+		}
+
+		err := p.Up(c, schema, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p plan) Copy(schema string, source string, destination string) error {
+	sourceConfig, err := p.connection(source, schema)
+	if err != nil {
+		return err
+	}
+
+	destinationConfig, err := p.connection(destination, schema)
+	if err != nil {
+		return err
+	}
+
+	sourceVersion, _, err := p.version(sourceConfig, schema)
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
+	}
+
+	destinationVersion, dirty, err := p.version(destinationConfig, schema)
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
+	}
+
+	return p.migrate(destination, destinationConfig, schema, destinationVersion, dirty, sourceVersion)
+}
+
+func (p plan) migrate(source string, dbConfig config.Connection, schema string, version uint, dirty bool, target uint) error {
+	versions, files, err := migrationFiles(p.config.Folder, schema)
+	if err != nil {
+		return Wrap(ERROR_NOT_FOUND, err)
+	}
+
+	repeatables, err := p.repeatables(dbConfig, schema)
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
+	}
+
+	p.render(source, schema, version, dirty, interleave(migrateSteps(versions, files, version, target), repeatables, false))
+
+	return nil
+}
+
+func (p plan) connection(source string, schema string) (config.Connection, error) {
+	dbConfig, ok := p.config.Connections[source]
+	if !ok {
+		return dbConfig, Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", source)
+	}
+
+	_, ok = dbConfig.Schemas[schema]
+	if !ok {
+		return dbConfig, Errorf(ERROR_NOT_FOUND, "schema '%s' not found on %s", schema, source)
+	}
+
+	return dbConfig, nil
+}
+
+func (p plan) repeatables(dbConfig config.Connection, schema string) ([]migrationStep, error) {
+	conn, err := config.NewConnection(dbConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	checksums, err := db.NewHistory(conn, schema).Checksums(DIRECTION_REPEATABLE)
+	if err != nil {
+		return nil, err
+	}
+
+	return repeatableSteps(p.config.Folder, schema, checksums)
+}
+
+func (p plan) version(dbConfig config.Connection, schema string) (uint, bool, error) {
+	conn, err := config.NewConnection(dbConfig)
+	if err != nil {
+		return 0, false, err
+	}
+
+	defer conn.Close()
+
+	var exists bool
+	err = conn.QueryRow(db.QUERY_MIGRATION_TABLE_EXISTS, fmt.Sprintf("%s.schema_migrations", schema)).Scan(&exists)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if !exists {
+		return 0, false, nil
+	}
+
+	var version int64
+	var dirty bool
+	err = conn.QueryRow(fmt.Sprintf(db.QUERY_MIGRATION_VERSION, schema)).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	if version < 0 {
+		return 0, dirty, nil
+	}
+
+	return uint(version), dirty, nil
+}
+
+func migrationFiles(folder string, schema string) ([]uint, map[uint]map[string]string, error) {
+	i, err := NewIndex(folder, schema)
+
+	return i.versions, i.files, err
+}
+
+func upSteps(versions []uint, files map[uint]map[string]string, version uint, limit int) []migrationStep {
+	steps := []migrationStep{}
+	for _, v := range versions {
+		if v <= version {
+			continue
+		}
+
+		if limit > 0 && len(steps) == limit {
+			break
+		}
+
+		steps = append(steps, migrationStep{version: v, target: v, direction: DIRECTION_UP, file: files[v][DIRECTION_UP]})
+	}
+
+	return steps
+}
+
+func downSteps(versions []uint, files map[uint]map[string]string, version uint, limit int) []migrationStep {
+	steps := []migrationStep{}
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i] > version {
+			continue
+		}
+
+		if limit > 0 && len(steps) == limit {
+			break
+		}
+
+		steps = append(steps, migrationStep{version: versions[i], target: previous(versions, i), direction: DIRECTION_DOWN, file: files[versions[i]][DIRECTION_DOWN]})
+	}
+
+	return steps
+}
+
+func migrateSteps(versions []uint, files map[uint]map[string]string, version uint, target uint) []migrationStep {
+	if target > version {
+		steps := []migrationStep{}
+		for _, v := range versions {
+			if v > version && v <= target {
+				steps = append(steps, migrationStep{version: v, target: v, direction: DIRECTION_UP, file: files[v][DIRECTION_UP]})
+			}
+		}
+
+		return steps
+	}
+
+	steps := []migrationStep{}
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i] > target && versions[i] <= version {
+			steps = append(steps, migrationStep{version: versions[i], target: previous(versions, i), direction: DIRECTION_DOWN, file: files[versions[i]][DIRECTION_DOWN]})
+		}
+	}
+
+	return steps
+}
+
+func repeatableSteps(folder string, schema string, checksums map[string]string) ([]migrationStep, error) {
+	steps := []migrationStep{}
+	entries, err := os.ReadDir(fmt.Sprintf("%s/%s/%s", folder, schema, REPEATABLE_FOLDER))
+	if os.IsNotExist(err) {
+		return steps, nil
+	}
+
+	if err != nil {
+		return steps, err
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || !repeatableFile.MatchString(e.Name()) {
+			continue
+		}
+
+		file := fmt.Sprintf("%s/%s", REPEATABLE_FOLDER, e.Name())
+		content, err := os.ReadFile(fmt.Sprintf("%s/%s/%s", folder, schema, file))
+		if err != nil {
+			return steps, err
+		}
+
+		sum := sha256.Sum256(content)
+		if checksums[file] == hex.EncodeToString(sum[:]) {
+			continue
+		}
+
+		steps = append(steps, migrationStep{after: parseHeader(string(content)).after, direction: DIRECTION_REPEATABLE, file: file})
+	}
+
+	return steps, nil
+}
+
+func interleave(steps []migrationStep, repeatables []migrationStep, final bool) []migrationStep {
+	result := []migrationStep{}
+	applied := map[string]bool{}
+	for _, s := range steps {
+		for _, r := range repeatables {
+			if s.direction == DIRECTION_UP && r.after > 0 && r.after < s.version && !applied[r.file] {
+				result = append(result, r)
+				applied[r.file] = true
+			}
+		}
+
+		result = append(result, s)
+	}
+
+	if !final {
+		return result
+	}
+
+	for _, r := range repeatables {
+		if !applied[r.file] {
+			result = append(result, r)
+		}
+	}
+
+	return result
+}
+
+func previous(versions []uint, i int) uint {
+	if i == 0 {
+		return 0
+	}
+
+	return versions[i-1]
+}
+
+func parseHeader(script string) header {
+	h := header{transaction: true, settings: map[string]string{}}
+	for _, line := range strings.Split(script, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+
+		annotation := strings.TrimSpace(strings.TrimPrefix(line, "--"))
+		if !strings.HasPrefix(annotation, config.ANNOTATION_PREFIX) {
+			continue
+		}
+
+		annotation = strings.TrimPrefix(annotation, config.ANNOTATION_PREFIX)
+		if annotation == config.ANNOTATION_NO_TRANSACTION {
+			h.transaction = false
+
+			continue
+		}
+
+		parts := strings.SplitN(annotation, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		name := strings.TrimSpace(parts[0])
+		if name == config.ANNOTATION_AFTER {
+			after, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 64)
+			if err == nil {
+				h.after = uint(after)
+			}
+
+			continue
+		}
+
+		for _, t := range timeouts {
+			if t == name {
+				h.settings[name] = strings.TrimSpace(parts[1])
+			}
+		}
+	}
+
+	return h
+}
+
+func (p plan) render(source string, schema string, version uint, dirty bool, steps []migrationStep) {
+	p.successColor.Printf("Plan for %s schema %s (current version %s)\n", p.boldFont.Sprint(source), p.boldFont.Sprint(schema), p.boldFont.Sprint(version))
+	if dirty {
+		p.errorColor.Printf("Version %s is dirty, clean the migration before running it\n", p.boldFont.Sprint(version))
+	}
+
+	if len(steps) == 0 {
+		p.successColor.Println("Nothing to run")
+
+		return
+	}
+
+	for i, s := range steps {
+		if s.file == "" {
+			p.errorColor.Printf("%d. [%s] %d migration file not found\n", i+1, strings.ToUpper(s.direction), s.version)
+
+			continue
+		}
+
+		content, err := os.ReadFile(fmt.Sprintf("%s/%s/%s", p.config.Folder, schema, s.file))
+		if err != nil {
+			fmt.Printf("%d. [%s] %s\n", i+1, p.boldFont.Sprint(strings.ToUpper(s.direction)), p.boldFont.Sprint(s.file))
+			p.errorColor.Println(err.Error())
+
+			continue
+		}
+
+		if parseHeader(string(content)).transaction {
+			fmt.Printf("%d. [%s] %s\n", i+1, p.boldFont.Sprint(strings.ToUpper(s.direction)), p.boldFont.Sprint(s.file))
+		} else {
+			fmt.Printf("%d. [%s] %s (no transaction)\n", i+1, p.boldFont.Sprint(strings.ToUpper(s.direction)), p.boldFont.Sprint(s.file))
+		}
+
+		fmt.Println(strings.TrimSpace(string(content)))
+		fmt.Println()
+	}
+}
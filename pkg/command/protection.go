@@ -0,0 +1,62 @@
+package command
+
+import (
+	"bufio"
+	"fmt"
+	"kmt/pkg/config"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+type protection struct {
+	config       config.Migration
+	reader       *bufio.Reader
+	boldFont     *color.Color
+	errorColor   *color.Color
+	successColor *color.Color
+}
+
+const (
+	ACTION_DROP     = "drop"
+	ACTION_SET      = "set"
+	ACTION_CLEAN    = "clean"
+	ACTION_ROLLBACK = "rollback"
+	ACTION_RESTORE  = "restore"
+)
+
+func NewProtection(config config.Migration) protection {
+	return protection{
+		config:       config,
+		reader:       bufio.NewReader(os.Stdin),
+		boldFont:     color.New(color.Bold),
+		errorColor:   color.New(color.FgRed),
+		successColor: color.New(color.FgGreen),
+	}
+}
+
+func (p protection) Confirm(source string, schema string, action string, yes bool) error {
+	dbConfig, ok := p.config.Connections[source]
+	if !ok || !dbConfig.Protected {
+		return nil
+	}
+
+	if action == ACTION_DROP && p.config.RefuseDrop {
+		return Errorf(ERROR_ABORTED, "drop is refused on protected connection '%s'", source)
+	}
+
+	if yes {
+		return nil
+	}
+
+	p.errorColor.Printf("%s is a protected connection, %s on schema %s may not be undone\n", p.boldFont.Sprint(source), p.boldFont.Sprint(action), p.boldFont.Sprint(schema))
+	fmt.Print("Type the connection name to confirm: ")
+
+	answer, _ := p.reader.ReadString('\n')
+	if strings.TrimSpace(answer) != source {
+		return Errorf(ERROR_ABORTED, "%s on %s schema %s cancelled", action, source, schema)
+	}
+
+	return nil
+}
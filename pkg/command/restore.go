@@ -0,0 +1,292 @@
+package command
+
+import (
+	"database/sql"
+	"fmt"
+	"kmt/pkg/config"
+	"kmt/pkg/db"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/briandowns/spinner"
+	"github.com/fatih/color"
+)
+
+type restore struct {
+	config       config.Migration
+	boldFont     *color.Color
+	errorColor   *color.Color
+	successColor *color.Color
+}
+
+func NewRestore(config config.Migration) restore {
+	return restore{
+		config:       config,
+		boldFont:     color.New(color.Bold),
+		errorColor:   color.New(color.FgRed),
+		successColor: color.New(color.FgGreen),
+	}
+}
+
+func (r restore) Call(source string, schema string, name string) error {
+	dbConfig, ok := r.config.Connections[source]
+	if !ok {
+		return Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", source)
+	}
+
+	_, ok = dbConfig.Schemas[schema]
+	if !ok {
+		return Errorf(ERROR_NOT_FOUND, "schema '%s' not found", schema)
+	}
+
+	path := name
+	_, err := os.Stat(path)
+	if err != nil {
+		folder := NewBackup(r.config).folder(source, schema)
+		path = fmt.Sprintf("%s/%s", folder, name)
+		if !strings.HasSuffix(path, BACKUP_EXTENSION) {
+			path = fmt.Sprintf("%s%s", path, BACKUP_EXTENSION)
+		}
+
+		_, err = os.Stat(path)
+		if err != nil {
+			return Errorf(ERROR_NOT_FOUND, "backup '%s' not found in %s", name, folder)
+		}
+	}
+
+	ctx, release, err := NewLock(r.config).Acquire(source, schema)
+	if err != nil {
+		return err
+	}
+
+	defer release()
+
+	conn, err := config.NewConnection(dbConfig)
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
+	}
+
+	defer conn.Close()
+
+	progress := spinner.New(spinner.CharSets[config.SPINER_INDEX], config.SPINER_DURATION)
+	progress.Suffix = fmt.Sprintf(" Restoring %s on %s schema %s", r.successColor.Sprint(path), r.successColor.Sprint(source), r.successColor.Sprint(schema))
+	progress.Start()
+
+	version, err := r.version(path, schema)
+	if err != nil {
+		progress.Stop()
+
+		return Errorf(ERROR_MIGRATION, "unable to read backup %s: %s", path, err.Error())
+	}
+
+	list, err := r.list(path, schema)
+	if err != nil {
+		progress.Stop()
+
+		return Errorf(ERROR_MIGRATION, "unable to read backup %s: %s", path, err.Error())
+	}
+
+	defer os.Remove(list)
+
+	previous := fmt.Sprintf("kmt_restore_%s_%d", schema, os.Getpid())
+	moved, err := r.replace(conn, schema, previous)
+	if err != nil {
+		progress.Stop()
+
+		return Wrap(ERROR_CONNECTION, err)
+	}
+
+	cli := exec.CommandContext(ctx, r.config.PgRestore, "--no-owner", "--exit-on-error", "--single-transaction", "--use-list", list, "--dbname", dbConfig.Conninfo(), path)
+	cli.Env = append(os.Environ(), fmt.Sprintf("PGPASSWORD=%s", dbConfig.Secret()))
+
+	output, err := cli.CombinedOutput()
+	if err != nil {
+		progress.Stop()
+
+		rErr := r.revert(conn, schema, previous, moved)
+		if rErr != nil {
+			return Errorf(ERROR_MIGRATION, "restore of %s schema %s failed: %s: %s, and the previous schema is left as %s: %s", source, schema, err.Error(), strings.TrimSpace(string(output)), previous, rErr.Error())
+		}
+
+		return Errorf(ERROR_MIGRATION, "restore of %s schema %s failed: %s: %s", source, schema, err.Error(), strings.TrimSpace(string(output)))
+	}
+
+	err = r.finish(conn, schema, previous, moved, version)
+	progress.Stop()
+	if err != nil {
+		return Errorf(ERROR_MIGRATION, "backup %s restored on %s schema %s but the previous schema is left as %s: %s", path, source, schema, previous, err.Error())
+	}
+
+	if version < 0 {
+		r.successColor.Printf("Backup %s restored on %s schema %s\n", r.boldFont.Sprint(path), r.boldFont.Sprint(source), r.boldFont.Sprint(schema))
+
+		return nil
+	}
+
+	r.successColor.Printf("Backup %s restored on %s schema %s, migration version is %s\n", r.boldFont.Sprint(path), r.boldFont.Sprint(source), r.boldFont.Sprint(schema), r.boldFont.Sprint(version))
+
+	return nil
+}
+
+func (r restore) version(path string, schema string) (int64, error) {
+	output, err := exec.Command(r.config.PgRestore, "--data-only", "--schema", schema, "--table", "schema_migrations", "--file", "-", path).Output()
+	if err != nil {
+		return -1, err
+	}
+
+	data := false
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.HasPrefix(line, "COPY ") {
+			data = true
+
+			continue
+		}
+
+		if !data {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if line == "\\." || len(fields) < 2 {
+			break
+		}
+
+		return strconv.ParseInt(fields[0], 10, 64)
+	}
+
+	return -1, nil
+}
+
+func (r restore) replace(conn *sql.DB, schema string, previous string) (bool, error) {
+	var exists bool
+	err := conn.QueryRow(db.QUERY_SCHEMA_EXISTS, schema).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	if exists {
+		_, err = tx.Exec(fmt.Sprintf(db.SQL_RENAME_SCHEMA, schema, previous))
+	}
+
+	if err == nil {
+		_, err = tx.Exec(fmt.Sprintf("CREATE SCHEMA %s", schema))
+	}
+
+	if err != nil {
+		tx.Rollback()
+
+		return false, err
+	}
+
+	return exists, tx.Commit()
+}
+
+func (r restore) revert(conn *sql.DB, schema string, previous string, moved bool) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", schema))
+	if err == nil && moved {
+		_, err = tx.Exec(fmt.Sprintf(db.SQL_RENAME_SCHEMA, previous, schema))
+	}
+
+	if err != nil {
+		tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r restore) finish(conn *sql.DB, schema string, previous string, moved bool, version int64) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+
+	var history bool
+	if moved {
+		err = tx.QueryRow(db.QUERY_HISTORY_EXISTS, fmt.Sprintf("%s.%s", previous, db.HISTORY_TABLE)).Scan(&history)
+	}
+
+	if err == nil && history {
+		_, err = tx.Exec(fmt.Sprintf(db.SQL_MOVE_TABLE, previous, db.HISTORY_TABLE, schema))
+	}
+
+	var migrations bool
+	if err == nil {
+		err = tx.QueryRow(db.QUERY_MIGRATION_TABLE_EXISTS, fmt.Sprintf("%s.schema_migrations", schema)).Scan(&migrations)
+	}
+
+	if err == nil && migrations {
+		_, err = tx.Exec(fmt.Sprintf(db.SQL_CLEAR_MIGRATION_VERSION, schema))
+	}
+
+	if err == nil && migrations && version >= 0 {
+		_, err = tx.Exec(fmt.Sprintf(db.SQL_SET_MIGRATION_VERSION, schema), version, false)
+	}
+
+	if err == nil && moved {
+		_, err = tx.Exec(fmt.Sprintf("DROP SCHEMA %s CASCADE", previous))
+	}
+
+	if err != nil {
+		tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r restore) list(path string, schema string) (string, error) {
+	output, err := exec.Command(r.config.PgRestore, "--list", path).Output()
+	if err != nil {
+		return "", err
+	}
+
+	var entries strings.Builder
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 5 && fields[3] == "SCHEMA" && fields[5] == schema {
+			continue
+		}
+
+		if len(fields) > 6 && fields[3] == "TABLE" && fields[4] == "DATA" && fields[6] == "schema_migrations" {
+			continue
+		}
+
+		skip := false
+		for _, f := range fields {
+			if strings.HasPrefix(f, db.HISTORY_TABLE) {
+				skip = true
+			}
+		}
+
+		if !skip {
+			entries.WriteString(line)
+			entries.WriteString("\n")
+		}
+	}
+
+	file, err := os.CreateTemp("", "kmt-restore-*.list")
+	if err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	_, err = file.WriteString(entries.String())
+
+	return file.Name(), err
+}
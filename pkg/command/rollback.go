@@ -5,6 +5,7 @@ import (
 	"kmt/pkg/config"
 
 	"github.com/fatih/color"
+	gomigrate "github.com/golang-migrate/migrate/v4"
 )
 
 type rollback struct {
@@ -25,46 +26,53 @@ func NewRollback(config config.Migration) rollback {
 
 func (r rollback) Call(source string, schema string, step int) error {
 	if step <= 0 {
-		r.errorColor.Println("Invalid step")
-
-		return nil
+		return Errorf(ERROR_INVALID, "invalid step")
 	}
 
 	dbConfig, ok := r.config.Connections[source]
 	if !ok {
-		r.errorColor.Printf("Database connection '%s' not found\n", r.boldFont.Sprint(source))
-
-		return nil
+		return Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", source)
 	}
 
 	_, ok = dbConfig.Schemas[schema]
 	if !ok {
-		r.errorColor.Printf("Schema '%s' not found\n", r.boldFont.Sprint(schema))
+		return Errorf(ERROR_NOT_FOUND, "schema '%s' not found", schema)
+	}
 
-		return nil
+	ctx, release, err := NewLock(r.config).Acquire(source, schema)
+	if err != nil {
+		return err
 	}
 
+	defer release()
+
 	db, err := config.NewConnection(dbConfig)
 	if err != nil {
-		r.errorColor.Println(err.Error())
-
-		return nil
+		return Wrap(ERROR_CONNECTION, err)
 	}
 
-	migrator := config.NewMigrator(db, dbConfig.Name, schema, fmt.Sprintf("%s/%s", r.config.Folder, schema))
-	err = migrator.Steps(step * -1)
+	migrator, err := config.NewMigrator(db, dbConfig.Name, schema, fmt.Sprintf("%s/%s", r.config.Folder, schema))
 	if err != nil {
-		r.errorColor.Println(err.Error())
+		return Wrap(ERROR_CONNECTION, err)
+	}
+
+	runner := newRunner(r.config.Folder, schema, dbConfig, db, migrator)
+	runner.ctx = ctx
+	runner.prepare = NewBackup(r.config).hook(source, schema)
+
+	err = runner.down(step)
+	if err != nil && err == gomigrate.ErrNoChange {
+		r.successColor.Printf("Database %s schema %s has nothing to roll back\n", r.boldFont.Sprint(source), r.boldFont.Sprint(schema))
 
 		return nil
 	}
 
-	version, dirty, _ := migrator.Version()
-	if version != 0 && dirty {
-		migrator.Force(int(version))
-		migrator.Steps(-1)
+	if err != nil {
+		return runner.failure(source, err)
 	}
 
+	version, _, _ := migrator.Version()
+
 	r.successColor.Printf("Migration rolled back to %s on %s schema %s\n", r.boldFont.Sprint(version), r.boldFont.Sprint(source), r.boldFont.Sprint(schema))
 
 	return nil
@@ -3,9 +3,6 @@ package command
 import (
 	"fmt"
 	"kmt/pkg/config"
-	"os"
-	"strconv"
-	"strings"
 
 	"github.com/briandowns/spinner"
 	"github.com/fatih/color"
@@ -29,65 +26,51 @@ func NewRun(config config.Migration) run {
 
 func (r run) Call(source string, schema string, step int) error {
 	if step <= 0 {
-		r.errorColor.Println("Invalid step")
-
-		return nil
+		return Errorf(ERROR_INVALID, "invalid step")
 	}
 
 	dbConfig, ok := r.config.Connections[source]
 	if !ok {
-		r.errorColor.Printf("Database connection '%s' not found\n", r.boldFont.Sprint(source))
-
-		return nil
+		return Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", source)
 	}
 
 	_, ok = dbConfig.Schemas[schema]
 	if !ok {
-		r.errorColor.Printf("Schema '%s' not found\n", r.boldFont.Sprint(schema))
+		return Errorf(ERROR_NOT_FOUND, "schema '%s' not found", schema)
+	}
 
-		return nil
+	ctx, release, err := NewLock(r.config).Acquire(source, schema)
+	if err != nil {
+		return err
 	}
 
+	defer release()
+
 	db, err := config.NewConnection(dbConfig)
 	if err != nil {
-		r.errorColor.Println(err.Error())
-
-		return nil
+		return Wrap(ERROR_CONNECTION, err)
 	}
 
-	files, err := os.ReadDir(fmt.Sprintf("%s/%s", r.config.Folder, schema))
+	migrator, err := config.NewMigrator(db, dbConfig.Name, schema, fmt.Sprintf("%s/%s", r.config.Folder, schema))
 	if err != nil {
-		r.errorColor.Println(err.Error())
-
-		return nil
+		return Wrap(ERROR_CONNECTION, err)
 	}
 
-	migrator := config.NewMigrator(db, dbConfig.Name, schema, fmt.Sprintf("%s/%s", r.config.Folder, schema))
-	version, _, _ := migrator.Version()
-	valid := false
-
-	migrations := []string{}
-	number := 0
-	for i, file := range files {
-		if i%2 == 0 {
-			continue
-		}
-
-		f := strings.Split(file.Name(), "_")
-		s, _ := strconv.Atoi(f[0])
-		if !valid && version == uint(s) {
-			valid = true
-
-			continue
-		}
+	runner := newRunner(r.config.Folder, schema, dbConfig, db, migrator)
+	runner.ctx = ctx
+	runner.prepare = NewBackup(r.config).hook(source, schema)
 
-		if valid && number < step {
-			migrations = append(migrations, f[0])
+	versions, files, version, err := runner.resolve()
+	if err != nil {
+		return Wrap(ERROR_MIGRATION, err)
+	}
 
-			number++
-		}
+	repeatables, err := runner.repeatables()
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
 	}
 
+	migrations := interleave(upSteps(versions, files, version, step), repeatables, false)
 	if len(migrations) == 0 {
 		r.successColor.Printf("Database %s schema %s is up to date\n", r.boldFont.Sprint(source), r.boldFont.Sprint(schema))
 
@@ -96,14 +79,14 @@ func (r run) Call(source string, schema string, step int) error {
 
 	for _, v := range migrations {
 		progress := spinner.New(spinner.CharSets[config.SPINER_INDEX], config.SPINER_DURATION)
-		progress.Suffix = fmt.Sprintf(" Run migration file %s on schema %s", r.successColor.Sprint(v), r.successColor.Sprint(schema))
+		progress.Suffix = fmt.Sprintf(" Run migration file %s on schema %s", r.successColor.Sprint(v.file), r.successColor.Sprint(schema))
+		progress.Start()
 
-		err = migrator.Steps(1)
+		err = runner.run([]migrationStep{v})
 		if err != nil {
 			progress.Stop()
-			r.errorColor.Printf("Error when running %s with message %s\n", r.boldFont.Sprint(v), r.boldFont.Sprint(err.Error()))
 
-			return nil
+			return runner.failure(source, err)
 		}
 
 		progress.Stop()
@@ -0,0 +1,501 @@
+package command
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"kmt/pkg/config"
+	"kmt/pkg/db"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/fatih/color"
+	gomigrate "github.com/golang-migrate/migrate/v4"
+	"github.com/lib/pq"
+)
+
+type (
+	runner struct {
+		folder     string
+		schema     string
+		settings   map[string]string
+		retries    int
+		db         *sql.DB
+		migrator   *gomigrate.Migrate
+		prepare    func() error
+		ctx        context.Context
+		boldFont   *color.Color
+		errorColor *color.Color
+	}
+
+	executor interface {
+		ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	}
+
+	mismatch struct {
+		version  uint
+		file     string
+		expected string
+		actual   string
+	}
+)
+
+func newRunner(folder string, schema string, connection config.Connection, db *sql.DB, migrator *gomigrate.Migrate) runner {
+	retries := config.LOCK_RETRIES
+	if connection.LockRetries != nil {
+		retries = *connection.LockRetries
+	}
+
+	return runner{
+		folder: folder,
+		schema: schema,
+		settings: map[string]string{
+			"lock_timeout":                        connection.LockTimeout,
+			"statement_timeout":                   connection.StatementTimeout,
+			"idle_in_transaction_session_timeout": connection.IdleInTransactionSessionTimeout,
+		},
+		retries:    retries,
+		db:         db,
+		migrator:   migrator,
+		ctx:        context.Background(),
+		boldFont:   color.New(color.Bold),
+		errorColor: color.New(color.FgRed),
+	}
+}
+
+func (r runner) resolve() ([]uint, map[uint]map[string]string, uint, error) {
+	versions, files, err := migrationFiles(r.folder, r.schema)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	version, _, err := r.migrator.Version()
+	if err == gomigrate.ErrNilVersion {
+		return versions, files, 0, nil
+	}
+
+	return versions, files, version, err
+}
+
+func (r runner) up(limit int) error {
+	versions, files, version, err := r.resolve()
+	if err != nil {
+		return err
+	}
+
+	repeatables, err := r.repeatables()
+	if err != nil {
+		return err
+	}
+
+	return r.run(interleave(upSteps(versions, files, version, limit), repeatables, limit == 0))
+}
+
+func (r runner) down(limit int) error {
+	versions, files, version, err := r.resolve()
+	if err != nil {
+		return err
+	}
+
+	return r.run(downSteps(versions, files, version, limit))
+}
+
+func (r runner) migrate(target uint) error {
+	versions, files, version, err := r.resolve()
+	if err != nil {
+		return err
+	}
+
+	repeatables, err := r.repeatables()
+	if err != nil {
+		return err
+	}
+
+	return r.run(interleave(migrateSteps(versions, files, version, target), repeatables, false))
+}
+
+func (r runner) repeatables() ([]migrationStep, error) {
+	checksums, err := db.NewHistory(r.db, r.schema).Checksums(DIRECTION_REPEATABLE)
+	if err != nil {
+		return nil, err
+	}
+
+	return repeatableSteps(r.folder, r.schema, checksums)
+}
+
+func (r runner) run(steps []migrationStep) error {
+	if len(steps) == 0 {
+		return gomigrate.ErrNoChange
+	}
+
+	version, dirty, err := r.migrator.Version()
+	if err == nil && dirty {
+		return gomigrate.ErrDirty{Version: int(version)}
+	}
+
+	if r.prepare != nil {
+		err := r.prepare()
+		if err != nil {
+			return err
+		}
+	}
+
+	osUser, host := identity()
+	for _, s := range steps {
+		if r.ctx.Err() != nil {
+			return fmt.Errorf("migration lock lost before running %s", s.file)
+		}
+
+		checksum, _ := fileChecksum(fmt.Sprintf("%s/%s/%s", r.folder, r.schema, s.file))
+
+		record := db.History{
+			Version:   s.version,
+			Direction: s.direction,
+			File:      s.file,
+			Checksum:  checksum,
+			User:      osUser,
+			Host:      host,
+			StartedAt: time.Now(),
+		}
+
+		err := r.step(s)
+
+		record.FinishedAt = time.Now()
+		record.Success = err == nil
+		if err != nil {
+			record.Message = err.Error()
+		}
+
+		hErr := db.NewHistory(r.db, r.schema).Record(record)
+		if hErr != nil {
+			r.errorColor.Printf("Unable to record history for %s: %s\n", s.file, hErr.Error())
+		}
+
+		if err != nil {
+			return fmt.Errorf("error when running %s: %w", s.file, err)
+		}
+	}
+
+	return nil
+}
+
+func (r runner) step(s migrationStep) error {
+	if s.file == "" {
+		return fmt.Errorf("migration file for version %d not found", s.version)
+	}
+
+	content, err := os.ReadFile(fmt.Sprintf("%s/%s/%s", r.folder, r.schema, s.file))
+	if err != nil {
+		return err
+	}
+
+	h := parseHeader(string(content))
+	settings := r.merge(h.settings)
+	if s.direction == DIRECTION_REPEATABLE {
+		if h.transaction {
+			return r.exec(string(content), settings)
+		}
+
+		return r.session(string(content), settings)
+	}
+
+	target := int64(s.target)
+	if s.target == 0 {
+		target = -1
+	}
+
+	if !h.transaction {
+		return r.direct(string(content), settings, int64(s.version), target)
+	}
+
+	backoff := config.LOCK_RETRY_BACKOFF
+	for attempt := 1; ; attempt++ {
+		err = r.transaction(string(content), settings, target)
+		if err == nil || attempt > r.retries || !lockTimeout(err) {
+			return err
+		}
+
+		r.errorColor.Printf("Lock timeout on %s, retrying in %s (%d/%d)\n", s.file, backoff, attempt, r.retries)
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (r runner) transaction(script string, settings map[string]string, target int64) error {
+	ctx := r.ctx
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = r.set(ctx, tx, settings, true)
+	if err == nil {
+		_, err = tx.ExecContext(ctx, script)
+	}
+
+	if err == nil {
+		err = r.version(ctx, tx, target, false)
+	}
+
+	if err != nil {
+		tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r runner) direct(script string, settings map[string]string, current int64, target int64) error {
+	ctx := r.ctx
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+	defer conn.ExecContext(context.Background(), "RESET ALL")
+
+	err = r.set(ctx, conn, settings, false)
+	if err != nil {
+		return err
+	}
+
+	err = r.version(ctx, conn, current, true)
+	if err != nil {
+		return err
+	}
+
+	err = r.each(ctx, conn, script)
+	if err != nil {
+		return err
+	}
+
+	return r.version(ctx, conn, target, false)
+}
+
+func (r runner) session(script string, settings map[string]string) error {
+	ctx := r.ctx
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+	defer conn.ExecContext(context.Background(), "RESET ALL")
+
+	_, err = conn.ExecContext(ctx, fmt.Sprintf("SET search_path TO %s", r.schema))
+	if err != nil {
+		return err
+	}
+
+	err = r.set(ctx, conn, settings, false)
+	if err != nil {
+		return err
+	}
+
+	return r.each(ctx, conn, script)
+}
+
+func (r runner) each(ctx context.Context, e executor, script string) error {
+	for _, s := range statements(script) {
+		backoff := config.LOCK_RETRY_BACKOFF
+		for attempt := 1; ; attempt++ {
+			_, err := e.ExecContext(ctx, s.text)
+			if err == nil {
+				break
+			}
+
+			if attempt > r.retries || !lockTimeout(err) {
+				return fmt.Errorf("statement at line %d: %w", s.line, err)
+			}
+
+			r.errorColor.Printf("Lock timeout on statement at line %d, retrying in %s (%d/%d)\n", s.line, backoff, attempt, r.retries)
+
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	return nil
+}
+
+func (r runner) exec(script string, settings map[string]string) error {
+	ctx := r.ctx
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL search_path TO %s", r.schema))
+	if err == nil {
+		err = r.set(ctx, tx, settings, true)
+	}
+
+	if err == nil {
+		_, err = tx.ExecContext(ctx, script)
+	}
+
+	if err != nil {
+		tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r runner) set(ctx context.Context, e executor, settings map[string]string, local bool) error {
+	scope := ""
+	if local {
+		scope = "LOCAL "
+	}
+
+	for _, name := range timeouts {
+		value, ok := settings[name]
+		if !ok {
+			continue
+		}
+
+		_, err := e.ExecContext(ctx, fmt.Sprintf("SET %s%s = %s", scope, name, pq.QuoteLiteral(value)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r runner) version(ctx context.Context, e executor, version int64, dirty bool) error {
+	_, err := e.ExecContext(ctx, fmt.Sprintf(db.SQL_CLEAR_MIGRATION_VERSION, r.schema))
+	if err != nil || (version < 0 && !dirty) {
+		return err
+	}
+
+	_, err = e.ExecContext(ctx, fmt.Sprintf(db.SQL_SET_MIGRATION_VERSION, r.schema), version, dirty)
+
+	return err
+}
+
+func (r runner) failure(source string, err error) error {
+	version, dirty, vErr := r.migrator.Version()
+	if vErr != nil || !dirty {
+		return Wrap(ERROR_MIGRATION, err)
+	}
+
+	return Errorf(ERROR_DIRTY, "%s, schema %s on %s is left dirty at version %d, fix the database manually then run 'kmt set %s %s <version>' with the version it is actually at", err.Error(), r.schema, source, version, source, r.schema)
+}
+
+func (r runner) merge(overrides map[string]string) map[string]string {
+	settings := map[string]string{}
+	for k, v := range r.settings {
+		if v != "" {
+			settings[k] = v
+		}
+	}
+
+	for k, v := range overrides {
+		settings[k] = v
+	}
+
+	return settings
+}
+
+func (r runner) verify() ([]mismatch, error) {
+	histories, err := db.NewHistory(r.db, r.schema).List()
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[uint]db.History{}
+	seen := map[uint]bool{}
+	versions := []uint{}
+	for _, h := range histories {
+		if !h.Success {
+			continue
+		}
+
+		if h.Direction == DIRECTION_DOWN {
+			delete(applied, h.Version)
+
+			continue
+		}
+
+		if h.Direction != DIRECTION_UP {
+			continue
+		}
+
+		if !seen[h.Version] {
+			seen[h.Version] = true
+			versions = append(versions, h.Version)
+		}
+
+		applied[h.Version] = h
+	}
+
+	mismatches := []mismatch{}
+	for _, v := range versions {
+		h, ok := applied[v]
+		if !ok {
+			continue
+		}
+
+		checksum, err := fileChecksum(fmt.Sprintf("%s/%s/%s", r.folder, r.schema, h.File))
+		if err != nil {
+			checksum = ""
+		}
+
+		if checksum != h.Checksum {
+			mismatches = append(mismatches, mismatch{version: v, file: h.File, expected: h.Checksum, actual: checksum})
+		}
+	}
+
+	return mismatches, nil
+}
+
+func (r runner) report(source string, mismatches []mismatch) {
+	r.errorColor.Printf("Applied migration files on %s schema %s have been modified:\n", r.boldFont.Sprint(source), r.boldFont.Sprint(r.schema))
+	for _, m := range mismatches {
+		if m.actual == "" {
+			r.errorColor.Printf("  - %s %s (file not found)\n", r.boldFont.Sprint(m.version), m.file)
+
+			continue
+		}
+
+		r.errorColor.Printf("  - %s %s (applied %.12s, current %.12s)\n", r.boldFont.Sprint(m.version), m.file, m.expected, m.actual)
+	}
+}
+
+func lockTimeout(err error) bool {
+	var e *pq.Error
+
+	return errors.As(err, &e) && e.Code == config.LOCK_NOT_AVAILABLE
+}
+
+func identity() (string, string) {
+	osUser := ""
+	current, err := user.Current()
+	if err == nil {
+		osUser = current.Username
+	}
+
+	host, _ := os.Hostname()
+
+	return osUser, host
+}
+
+func fileChecksum(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -3,9 +3,6 @@ package command
 import (
 	"fmt"
 	"kmt/pkg/config"
-	"os"
-	"strconv"
-	"strings"
 
 	"github.com/fatih/color"
 )
@@ -28,62 +25,48 @@ func NewSet(config config.Migration) set {
 
 func (s set) Call(source string, schema string, version int) error {
 	if version <= 0 {
-		s.errorColor.Println("Invalid version")
-
-		return nil
+		return Errorf(ERROR_INVALID, "invalid version")
 	}
 
-	files, err := os.ReadDir(fmt.Sprintf("%s/%s", s.config.Folder, schema))
+	i, err := NewIndex(s.config.Folder, schema)
 	if err != nil {
-		s.errorColor.Println(err.Error())
-
-		return nil
+		return err
 	}
 
-	valid := false
-	for _, file := range files {
-		f := strings.Split(file.Name(), "_")
-		s, _ := strconv.Atoi(f[0])
-		if version == s {
-			valid = true
-
-			break
-		}
-	}
-
-	if !valid {
-		s.errorColor.Printf("Migration file for version %s not found\n", s.boldFont.Sprint(version))
-
-		return nil
+	if !i.has(uint(version)) {
+		return Errorf(ERROR_NOT_FOUND, "migration file for version %d not found", version)
 	}
 
 	dbConfig, ok := s.config.Connections[source]
 	if !ok {
-		s.errorColor.Printf("Database connection '%s' not found\n", s.boldFont.Sprint(source))
-
-		return nil
+		return Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", source)
 	}
 
 	_, ok = dbConfig.Schemas[schema]
 	if !ok {
-		s.errorColor.Printf("Schema '%s' not found\n", s.boldFont.Sprint(schema))
+		return Errorf(ERROR_NOT_FOUND, "schema '%s' not found", schema)
+	}
 
-		return nil
+	_, release, err := NewLock(s.config).Acquire(source, schema)
+	if err != nil {
+		return err
 	}
 
+	defer release()
+
 	db, err := config.NewConnection(dbConfig)
 	if err != nil {
-		s.errorColor.Println(err.Error())
+		return Wrap(ERROR_CONNECTION, err)
+	}
 
-		return nil
+	migrator, err := config.NewMigrator(db, dbConfig.Name, schema, fmt.Sprintf("%s/%s", s.config.Folder, schema))
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
 	}
 
-	migrator := config.NewMigrator(db, dbConfig.Name, schema, fmt.Sprintf("%s/%s", s.config.Folder, schema))
 	err = migrator.Force(version)
 	if err != nil {
-		s.errorColor.Println(err.Error())
-
-		return nil
+		return Wrap(ERROR_MIGRATION, err)
 	}
 
 	s.successColor.Printf("Migration on %s schema %s set to %s\n", s.boldFont.Sprint(source), s.boldFont.Sprint(schema), s.boldFont.Sprint(version))
@@ -0,0 +1,108 @@
+package command
+
+import "strings"
+
+type statement struct {
+	text string
+	line int
+}
+
+func statements(script string) []statement {
+	result := []statement{}
+
+	var current strings.Builder
+	line := 1
+	start := 0
+	flush := func() {
+		text := strings.TrimSpace(current.String())
+		if text != "" {
+			result = append(result, statement{text: text, line: start})
+		}
+
+		current.Reset()
+		start = 0
+	}
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case strings.HasPrefix(script[i:], "--"):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				i = len(script)
+
+				continue
+			}
+
+			i += end - 1
+
+			continue
+		case strings.HasPrefix(script[i:], "/*"):
+			body := script[i:]
+			end := strings.Index(script[i+2:], "*/")
+			if end >= 0 {
+				body = script[i : i+end+4]
+			}
+
+			line += strings.Count(body, "\n")
+			current.WriteByte(' ')
+			i += len(body) - 1
+
+			continue
+		case c == ';':
+			flush()
+
+			continue
+		}
+
+		if start == 0 && !strings.ContainsRune(" \t\r\n", rune(c)) {
+			start = line
+		}
+
+		quote := ""
+		if c == '\'' || c == '"' {
+			quote = string(c)
+		} else if c == '$' {
+			quote = dollarTag(script[i:])
+		}
+
+		if quote != "" {
+			body := script[i:]
+			end := strings.Index(script[i+len(quote):], quote)
+			if end >= 0 {
+				body = script[i : i+end+2*len(quote)]
+			}
+
+			line += strings.Count(body, "\n")
+			current.WriteString(body)
+			i += len(body) - 1
+
+			continue
+		}
+
+		if c == '\n' {
+			line++
+		}
+
+		current.WriteByte(c)
+	}
+
+	flush()
+
+	return result
+}
+
+func dollarTag(script string) string {
+	for i := 1; i < len(script); i++ {
+		c := script[i]
+		if c == '$' {
+			return script[:i+1]
+		}
+
+		if !(c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 1 && c >= '0' && c <= '9')) {
+			return ""
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,184 @@
+package command
+
+import (
+	"kmt/pkg/config"
+	"kmt/pkg/db"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+type status struct {
+	config       config.Migration
+	boldFont     *color.Color
+	errorColor   *color.Color
+	successColor *color.Color
+}
+
+const (
+	STATE_APPLIED = "applied"
+	STATE_CURRENT = "current"
+	STATE_DIRTY   = "dirty"
+	STATE_PENDING = "pending"
+	STATE_MISSING = "missing on disk"
+)
+
+func NewStatus(config config.Migration) status {
+	return status{
+		config:       config,
+		boldFont:     color.New(color.Bold),
+		errorColor:   color.New(color.FgRed),
+		successColor: color.New(color.FgGreen),
+	}
+}
+
+func (s status) Call(target string, schema string) error {
+	connections, ok := s.config.Clusters[target]
+	if !ok {
+		connections = []string{target}
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"No", "Connection", "Schema", "Version", "Date", "Migration File", "State", "Applied At"})
+
+	number := 1
+	for _, c := range connections {
+		dbConfig, ok := s.config.Connections[c]
+		if !ok {
+			return Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", c)
+		}
+
+		schemas := []string{schema}
+		if schema == "" {
+			schemas = []string{}
+			for k := range dbConfig.Schemas {
+				schemas = append(schemas, k)
+			}
+
+			sort.Strings(schemas)
+		}
+
+		for _, x := range schemas {
+			_, ok = dbConfig.Schemas[x]
+			if !ok {
+				return Errorf(ERROR_NOT_FOUND, "schema '%s' not found on %s", x, c)
+			}
+
+			rows, err := s.rows(dbConfig, x)
+			if err != nil {
+				return err
+			}
+
+			for _, r := range rows {
+				t.AppendRow(append(table.Row{number, c, x}, r...))
+
+				number++
+			}
+		}
+	}
+
+	t.Render()
+
+	return nil
+}
+
+func (s status) rows(dbConfig config.Connection, schema string) ([]table.Row, error) {
+	i, err := NewIndex(s.config.Folder, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	version, dirty, err := NewPlan(s.config).version(dbConfig, schema)
+	if err != nil {
+		return nil, Wrap(ERROR_CONNECTION, err)
+	}
+
+	conn, err := config.NewConnection(dbConfig)
+	if err != nil {
+		return nil, Wrap(ERROR_CONNECTION, err)
+	}
+
+	defer conn.Close()
+
+	histories, err := db.NewHistory(conn, schema).List()
+	if err != nil {
+		return nil, Wrap(ERROR_CONNECTION, err)
+	}
+
+	applied := map[uint]db.History{}
+	for _, h := range histories {
+		if !h.Success {
+			continue
+		}
+
+		switch h.Direction {
+		case DIRECTION_UP:
+			applied[h.Version] = h
+		case DIRECTION_DOWN:
+			delete(applied, h.Version)
+		}
+	}
+
+	versions := append([]uint{}, i.versions...)
+	for v := range applied {
+		if !i.has(v) && v <= version {
+			versions = append(versions, v)
+		}
+	}
+
+	if version > 0 && !i.has(version) {
+		_, ok := applied[version]
+		if !ok {
+			versions = append(versions, version)
+		}
+	}
+
+	sort.Slice(versions, func(x, y int) bool {
+		return versions[x] < versions[y]
+	})
+
+	rows := []table.Row{}
+	for _, v := range versions {
+		state := STATE_PENDING
+		switch {
+		case !i.has(v):
+			state = STATE_MISSING
+		case v == version && dirty:
+			state = STATE_DIRTY
+		case v == version:
+			state = STATE_CURRENT
+		case v < version:
+			state = STATE_APPLIED
+		}
+
+		file := i.files[v][DIRECTION_UP]
+		if file == "" {
+			file = "-"
+		}
+
+		appliedAt := "-"
+		h, ok := applied[v]
+		if ok && v <= version {
+			appliedAt = h.FinishedAt.Local().Format(time.RFC3339)
+		}
+
+		rows = append(rows, table.Row{v, time.Unix(int64(v), 0).Local().Format(time.RFC3339), file, s.colorize(state), appliedAt})
+	}
+
+	return rows, nil
+}
+
+func (s status) colorize(state string) string {
+	switch state {
+	case STATE_CURRENT, STATE_APPLIED:
+		return s.successColor.Sprint(state)
+	case STATE_DIRTY, STATE_MISSING:
+		return color.New(color.FgRed, color.Bold).Sprint(state)
+	}
+
+	return s.boldFont.Sprint(state)
+}
@@ -1,20 +1,37 @@
 package command
 
 import (
+	"context"
 	"fmt"
 	"kmt/pkg/config"
+	"os"
+	iSync "sync"
+	"time"
 
 	"github.com/briandowns/spinner"
 	"github.com/fatih/color"
 	gomigrate "github.com/golang-migrate/migrate/v4"
+	"github.com/jedib0t/go-pretty/v6/table"
 )
 
-type sync struct {
-	config       config.Migration
-	boldFont     *color.Color
-	errorColor   *color.Color
-	successColor *color.Color
-}
+type (
+	sync struct {
+		config       config.Migration
+		boldFont     *color.Color
+		errorColor   *color.Color
+		successColor *color.Color
+	}
+
+	node struct {
+		connection string
+		before     uint
+		after      uint
+		duration   time.Duration
+		skipped    bool
+		rolledBack bool
+		err        error
+	}
+)
 
 func NewSync(config config.Migration) sync {
 	return sync{
@@ -25,71 +42,384 @@ func NewSync(config config.Migration) sync {
 	}
 }
 
-func (s sync) Run(cluster string, schema string) error {
-	lists, ok := s.config.Clusters[cluster]
-	if !ok {
-		s.errorColor.Printf("Cluster '%s' isn't defined\n", s.boldFont.Sprint(cluster))
+func (s sync) Run(cluster string, schema string, parallel int, continueOnError bool) error {
+	connections, err := s.nodes(cluster)
+	if err != nil {
+		return err
+	}
 
-		return nil
+	ctx, release, err := NewLock(s.config).Acquire(cluster, schema)
+	if err != nil {
+		return err
 	}
 
-	connection := make(chan config.Connection)
-	name := make(chan string)
+	defer release()
 
-	go func(source string, conns []string, cConfigs map[string]config.Connection, connection chan<- config.Connection, name chan<- string) {
-		for _, c := range conns {
-			if source == c {
-				continue
-			}
+	progress := spinner.New(spinner.CharSets[config.SPINER_INDEX], config.SPINER_DURATION)
+	progress.Suffix = fmt.Sprintf(" Syncing %s node(s) on %s schema %s", s.successColor.Sprint(len(connections)), s.successColor.Sprint(cluster), s.successColor.Sprint(schema))
+	progress.Start()
+
+	nodes := s.batch(ctx, connections, schema, parallel, continueOnError)
+
+	progress.Stop()
+
+	s.summary(nodes)
+
+	for _, n := range nodes {
+		if n.err != nil {
+			return fmt.Errorf("sync on %s failed: %w", n.connection, n.err)
+		}
+	}
+
+	s.successColor.Printf("Migration synced on %s schema %s\n", s.boldFont.Sprint(cluster), s.boldFont.Sprint(schema))
+
+	return nil
+}
+
+func (s sync) Canary(cluster string, schema string, canary string, batch int) error {
+	connections, err := s.nodes(cluster)
+	if err != nil {
+		return err
+	}
+
+	ctx, release, err := NewLock(s.config).Acquire(cluster, schema)
+	if err != nil {
+		return err
+	}
+
+	defer release()
+
+	setting := s.config.Canaries[cluster]
+	if canary == "" {
+		canary = setting.Node
+	}
+
+	if canary == "" && len(connections) > 0 {
+		canary = connections[0]
+	}
+
+	if batch < 1 {
+		batch = setting.Batch
+	}
+
+	if batch < 1 {
+		batch = 1
+	}
+
+	remaining := []string{}
+	for _, c := range connections {
+		if c != canary {
+			remaining = append(remaining, c)
+		}
+	}
+
+	if len(remaining) == len(connections) {
+		return Errorf(ERROR_INVALID, "canary '%s' isn't a node of cluster '%s'", canary, cluster)
+	}
+
+	versions, _, err := migrationFiles(s.config.Folder, schema)
+	if err != nil {
+		return Wrap(ERROR_NOT_FOUND, err)
+	}
+
+	var latest uint
+	if len(versions) > 0 {
+		latest = versions[len(versions)-1]
+	}
 
-			x, ok := cConfigs[c]
-			if !ok {
-				s.errorColor.Printf("Connection '%s' isn't defined\n", s.boldFont.Sprint(c))
+	progress := spinner.New(spinner.CharSets[config.SPINER_INDEX], config.SPINER_DURATION)
+	progress.Suffix = fmt.Sprintf(" Migrating canary %s on %s schema %s", s.successColor.Sprint(canary), s.successColor.Sprint(cluster), s.successColor.Sprint(schema))
+	progress.Start()
 
-				close(connection)
+	nodes := s.batch(ctx, []string{canary}, schema, 1, true)
+	if nodes[0].err == nil {
+		nodes[0].err = s.check(canary, schema, latest, setting.Query)
+	}
+
+	failed := nodes[0].err != nil
+	for i := 0; !failed && i < len(remaining); i += batch {
+		end := i + batch
+		if end > len(remaining) {
+			end = len(remaining)
+		}
 
-				break
+		progress.Suffix = fmt.Sprintf(" Migrating batch %s on %s schema %s", s.successColor.Sprint(i/batch+1), s.successColor.Sprint(cluster), s.successColor.Sprint(schema))
+
+		results := s.batch(ctx, remaining[i:end], schema, batch, true)
+		for _, r := range results {
+			if r.err != nil {
+				failed = true
 			}
+		}
 
-			connection <- x
-			name <- c
+		nodes = append(nodes, results...)
+	}
+
+	progress.Stop()
+
+	var failure *node
+	for i := range nodes {
+		if nodes[i].err != nil {
+			failure = &nodes[i]
+
+			break
 		}
+	}
+
+	if failure == nil {
+		s.summary(nodes)
+
+		s.successColor.Printf("Migration synced on %s schema %s\n", s.boldFont.Sprint(cluster), s.boldFont.Sprint(schema))
+
+		return nil
+	}
+
+	err = fmt.Errorf("sync on %s failed: %w", failure.connection, failure.err)
+
+	progress.Suffix = fmt.Sprintf(" Rolling back %s schema %s", s.successColor.Sprint(cluster), s.successColor.Sprint(schema))
+	progress.Start()
 
-		close(connection)
-		close(name)
-	}(s.config.Source, lists, s.config.Connections, connection, name)
+	for i := range nodes {
+		if nodes[i].after == nodes[i].before {
+			continue
+		}
 
-	for source := range connection {
-		db, err := config.NewConnection(source)
-		if err != nil {
-			s.errorColor.Println(err.Error())
+		rErr := s.rollback(nodes[i].connection, schema, nodes[i].before)
+		if rErr != nil {
+			s.errorColor.Printf("Unable to rollback %s: %s\n", nodes[i].connection, rErr.Error())
 
-			return nil
+			continue
 		}
 
-		migrator := config.NewMigrator(db, source.Name, schema, fmt.Sprintf("%s/%s", s.config.Folder, schema))
+		nodes[i].after = nodes[i].before
+		nodes[i].rolledBack = true
+	}
 
-		progress := spinner.New(spinner.CharSets[config.SPINER_INDEX], config.SPINER_DURATION)
-		progress.Suffix = fmt.Sprintf(" Running migrations for %s on %s schema", s.successColor.Sprint(<-name), s.successColor.Sprint(schema))
-		progress.Start()
+	progress.Stop()
 
-		err = migrator.Up()
-		if err != nil && err == gomigrate.ErrNoChange {
-			progress.Stop()
+	for _, c := range remaining[len(nodes)-1:] {
+		nodes = append(nodes, node{connection: c, skipped: true})
+	}
 
+	s.summary(nodes)
+
+	return err
+}
+
+func (s sync) nodes(cluster string) ([]string, error) {
+	lists, ok := s.config.Clusters[cluster]
+	if !ok {
+		return nil, Errorf(ERROR_NOT_FOUND, "cluster '%s' isn't defined", cluster)
+	}
+
+	connections := []string{}
+	for _, c := range lists {
+		if s.config.Source == c {
 			continue
 		}
 
-		version, dirty, _ := migrator.Version()
-		if version != 0 && dirty {
-			migrator.Force(int(version))
-			migrator.Steps(-1)
+		_, ok := s.config.Connections[c]
+		if !ok {
+			return nil, Errorf(ERROR_NOT_FOUND, "connection '%s' isn't defined", c)
 		}
 
-		progress.Stop()
+		connections = append(connections, c)
 	}
 
-	s.successColor.Printf("Migration synced on %s schema %s\n", s.boldFont.Sprint(cluster), s.boldFont.Sprint(schema))
+	return connections, nil
+}
+
+func (s sync) batch(ctx context.Context, connections []string, schema string, parallel int, continueOnError bool) []node {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	nodes := make([]node, len(connections))
+	failed := false
+
+	var mutex iSync.Mutex
+	var wg iSync.WaitGroup
+	queue := make(chan int, parallel)
+	for i, c := range connections {
+		queue <- i
+
+		mutex.Lock()
+		stop := failed && !continueOnError
+		mutex.Unlock()
+
+		if stop {
+			<-queue
+			nodes[i] = node{connection: c, skipped: true}
+
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, c string) {
+			defer wg.Done()
+
+			start := time.Now()
+			nodes[i] = s.sync(ctx, c, schema)
+			nodes[i].duration = time.Since(start)
+			if nodes[i].err != nil {
+				mutex.Lock()
+				failed = true
+				mutex.Unlock()
+			}
+
+			<-queue
+		}(i, c)
+	}
+
+	wg.Wait()
+
+	return nodes
+}
+
+func (s sync) sync(ctx context.Context, connection string, schema string) node {
+	result := node{connection: connection}
+
+	source := s.config.Connections[connection]
+
+	db, err := config.NewConnection(source)
+	if err != nil {
+		result.err = Wrap(ERROR_CONNECTION, err)
+
+		return result
+	}
+
+	defer db.Close()
+
+	migrator, err := config.NewMigrator(db, source.Name, schema, fmt.Sprintf("%s/%s", s.config.Folder, schema))
+	if err != nil {
+		result.err = Wrap(ERROR_CONNECTION, err)
+
+		return result
+	}
+
+	result.before, _, _ = migrator.Version()
+	result.after = result.before
+
+	runner := newRunner(s.config.Folder, schema, source, db, migrator)
+	runner.ctx = ctx
+	runner.prepare = NewBackup(s.config).hook(connection, schema)
+
+	mismatches, err := runner.verify()
+	if err != nil {
+		result.err = Wrap(ERROR_CONNECTION, err)
+
+		return result
+	}
+
+	if len(mismatches) > 0 {
+		runner.report(connection, mismatches)
+
+		result.err = Errorf(ERROR_DRIFT, "applied migration files on %s schema %s have been modified", connection, schema)
+
+		return result
+	}
+
+	err = runner.up(0)
+	if err != nil && err != gomigrate.ErrNoChange {
+		result.err = runner.failure(connection, err)
+		result.after, _, _ = migrator.Version()
+
+		return result
+	}
+
+	result.after, _, _ = migrator.Version()
+
+	return result
+}
+
+func (s sync) check(connection string, schema string, latest uint, query string) error {
+	db, err := config.NewConnection(s.config.Connections[connection])
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
+	}
+
+	defer db.Close()
+
+	migrator, err := config.NewMigrator(db, s.config.Connections[connection].Name, schema, fmt.Sprintf("%s/%s", s.config.Folder, schema))
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
+	}
+
+	version, dirty, err := migrator.Version()
+	if err != nil && err != gomigrate.ErrNilVersion {
+		return Wrap(ERROR_MIGRATION, err)
+	}
+
+	if dirty {
+		return Errorf(ERROR_DIRTY, "canary %s version %d is dirty", connection, version)
+	}
+
+	if version != latest {
+		return Errorf(ERROR_MIGRATION, "canary %s is on version %d, expected %d", connection, version, latest)
+	}
+
+	if query == "" {
+		return nil
+	}
+
+	var healthy bool
+	err = db.QueryRow(query).Scan(&healthy)
+	if err != nil {
+		return Errorf(ERROR_MIGRATION, "health query on canary %s failed: %w", connection, err)
+	}
+
+	if !healthy {
+		return Errorf(ERROR_MIGRATION, "health query on canary %s returned false", connection)
+	}
 
 	return nil
 }
+
+func (s sync) rollback(connection string, schema string, version uint) error {
+	source := s.config.Connections[connection]
+
+	db, err := config.NewConnection(source)
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	migrator, err := config.NewMigrator(db, source.Name, schema, fmt.Sprintf("%s/%s", s.config.Folder, schema))
+	if err != nil {
+		return err
+	}
+
+	err = newRunner(s.config.Folder, schema, source, db, migrator).migrate(version)
+	if err == gomigrate.ErrNoChange {
+		return nil
+	}
+
+	return err
+}
+
+func (s sync) summary(nodes []node) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"No", "Connection", "Before", "After", "Duration", "Status"})
+
+	for i, n := range nodes {
+		var status string
+		switch {
+		case n.skipped:
+			status = s.boldFont.Sprint("skipped")
+		case n.rolledBack && n.err == nil:
+			status = s.boldFont.Sprint("rolled back")
+		case n.rolledBack:
+			status = color.New(color.FgRed, color.Bold).Sprintf("x %s, rolled back", n.err.Error())
+		case n.err != nil:
+			status = color.New(color.FgRed, color.Bold).Sprintf("x %s", n.err.Error())
+		default:
+			status = s.successColor.Sprint("✔")
+		}
+
+		t.AppendRow(table.Row{i + 1, n.connection, n.before, n.after, n.duration.Round(time.Millisecond), status})
+	}
+
+	t.Render()
+}
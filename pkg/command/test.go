@@ -35,27 +35,22 @@ func (t test) Call() error {
 		progress.Suffix = fmt.Sprintf(" Test connection to %s...", t.successColor.Sprint(i))
 		progress.Start()
 
-		db, err := config.NewConnection(c)
+		err := ping(c)
 		if err != nil {
 			progress.Stop()
 
-			t.errorColor.Println(err.Error())
-
-			return nil
+			return Errorf(ERROR_CONNECTION, "connection '%s' error %s", i, err.Error())
 		}
+	}
 
-		_, err = db.Query("SELECT 1")
-		if err != nil {
-			progress.Stop()
+	progress.Stop()
 
-			t.errorColor.Printf("Connection '%s' error %s \n", i, err.Error())
+	if t.config.Generator != config.GENERATOR_PG_DUMP && !t.config.Backup {
+		t.successColor.Println("Config test passed")
 
-			return nil
-		}
+		return nil
 	}
 
-	progress.Stop()
-
 	progress.Suffix = fmt.Sprintf(" Test '%s' command...", t.successColor.Sprint("pg_dump"))
 	progress.Start()
 
@@ -64,9 +59,7 @@ func (t test) Call() error {
 	if err != nil {
 		progress.Stop()
 
-		t.errorColor.Printf("'pg_dump' command not found on %s\n", t.boldFont.Sprint(t.config.PgDump))
-
-		return nil
+		return Errorf(ERROR_NOT_FOUND, "'pg_dump' command not found on %s", t.config.PgDump)
 	}
 
 	progress.Stop()
@@ -75,3 +68,16 @@ func (t test) Call() error {
 
 	return nil
 }
+
+func ping(connection config.Connection) error {
+	db, err := config.NewConnection(connection)
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	_, err = db.Exec("SELECT 1")
+
+	return err
+}
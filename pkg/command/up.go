@@ -28,56 +28,68 @@ func NewUp(config config.Migration) up {
 func (u up) Call(source string, schema string) error {
 	dbConfig, ok := u.config.Connections[source]
 	if !ok {
-		u.errorColor.Printf("Database connection '%s' not found\n", u.boldFont.Sprint(source))
-
-		return nil
+		return Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", source)
 	}
 
 	_, ok = dbConfig.Schemas[schema]
 	if !ok {
-		u.errorColor.Printf("Schema '%s' not found\n", u.boldFont.Sprint(schema))
+		return Errorf(ERROR_NOT_FOUND, "schema '%s' not found", schema)
+	}
 
-		return nil
+	ctx, release, err := NewLock(u.config).Acquire(source, schema)
+	if err != nil {
+		return err
 	}
 
+	defer release()
+
 	db, err := config.NewConnection(dbConfig)
 	if err != nil {
-		u.errorColor.Println(err.Error())
-
-		return nil
+		return Wrap(ERROR_CONNECTION, err)
 	}
 
 	_, err = db.Exec(fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", schema))
 	if err != nil {
-		u.errorColor.Println(err.Error())
+		return Wrap(ERROR_CONNECTION, err)
+	}
 
-		return nil
+	migrator, err := config.NewMigrator(db, dbConfig.Name, schema, fmt.Sprintf("%s/%s", u.config.Folder, schema))
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
 	}
 
-	migrator := config.NewMigrator(db, dbConfig.Name, schema, fmt.Sprintf("%s/%s", u.config.Folder, schema))
+	runner := newRunner(u.config.Folder, schema, dbConfig, db, migrator)
+	runner.ctx = ctx
+	runner.prepare = NewBackup(u.config).hook(source, schema)
+
+	mismatches, err := runner.verify()
+	if err != nil {
+		return Wrap(ERROR_CONNECTION, err)
+	}
+
+	if len(mismatches) > 0 {
+		runner.report(source, mismatches)
+
+		return Errorf(ERROR_DRIFT, "applied migration files on %s schema %s have been modified", source, schema)
+	}
 
 	progress := spinner.New(spinner.CharSets[config.SPINER_INDEX], config.SPINER_DURATION)
 	progress.Suffix = fmt.Sprintf(" Running migrations for %s on %s schema", u.successColor.Sprint(source), u.successColor.Sprint(schema))
 	progress.Start()
 
-	err = migrator.Up()
+	err = runner.up(0)
+	progress.Stop()
 	if err != nil && err == gomigrate.ErrNoChange {
-		progress.Stop()
-
 		u.successColor.Printf("Database %s schema %s is up to date\n", u.boldFont.Sprint(source), u.boldFont.Sprint(schema))
 
 		return nil
 	}
 
-	version, dirty, _ := migrator.Version()
-	if version != 0 && dirty {
-		migrator.Force(int(version))
-		migrator.Steps(-1)
+	if err != nil {
+		return runner.failure(source, err)
 	}
 
-	progress.Stop()
-
 	u.successColor.Printf("Migration on %s schema %s run successfully\n", u.boldFont.Sprint(source), u.boldFont.Sprint(schema))
 
-	return err
+	return nil
 }
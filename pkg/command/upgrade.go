@@ -44,9 +44,8 @@ func (u upgrade) Call() error {
 	})
 	if err != nil {
 		progress.Stop()
-		u.errorColor.Println(err)
 
-		return nil
+		return err
 	}
 
 	var latest string
@@ -55,9 +54,8 @@ func (u upgrade) Call() error {
 	tags, err := repository.TagObjects()
 	if err != nil {
 		progress.Stop()
-		u.errorColor.Println(err)
 
-		return nil
+		return err
 	}
 
 	_ = tags.ForEach(func(t *object.Tag) error {
@@ -86,9 +84,8 @@ func (u upgrade) Call() error {
 	err = cmd.Run()
 	if err != nil {
 		progress.Stop()
-		u.errorColor.Println("Error checkout to latest tag")
 
-		return nil
+		return fmt.Errorf("error checkout to latest tag: %w", err)
 	}
 
 	cmd = exec.Command("go", "get")
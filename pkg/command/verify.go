@@ -0,0 +1,70 @@
+package command
+
+import (
+	"kmt/pkg/config"
+
+	"github.com/fatih/color"
+)
+
+type verify struct {
+	config       config.Migration
+	boldFont     *color.Color
+	errorColor   *color.Color
+	successColor *color.Color
+}
+
+func NewVerify(config config.Migration) verify {
+	return verify{
+		config:       config,
+		boldFont:     color.New(color.Bold),
+		errorColor:   color.New(color.FgRed),
+		successColor: color.New(color.FgGreen),
+	}
+}
+
+func (v verify) Call(target string, schema string) error {
+	connections, ok := v.config.Clusters[target]
+	if !ok {
+		connections = []string{target}
+	}
+
+	failed := false
+	for _, c := range connections {
+		dbConfig, ok := v.config.Connections[c]
+		if !ok {
+			return Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", c)
+		}
+
+		_, ok = dbConfig.Schemas[schema]
+		if !ok {
+			return Errorf(ERROR_NOT_FOUND, "schema '%s' not found on %s", schema, c)
+		}
+
+		db, err := config.NewConnection(dbConfig)
+		if err != nil {
+			return Wrap(ERROR_CONNECTION, err)
+		}
+
+		runner := newRunner(v.config.Folder, schema, dbConfig, db, nil)
+		mismatches, err := runner.verify()
+		db.Close()
+		if err != nil {
+			return Wrap(ERROR_CONNECTION, err)
+		}
+
+		if len(mismatches) > 0 {
+			runner.report(c, mismatches)
+			failed = true
+
+			continue
+		}
+
+		v.successColor.Printf("Migration files on %s schema %s match applied checksums\n", v.boldFont.Sprint(c), v.boldFont.Sprint(schema))
+	}
+
+	if failed {
+		return Errorf(ERROR_DRIFT, "checksum verification failed on schema %s", schema)
+	}
+
+	return nil
+}
@@ -3,11 +3,9 @@ package command
 import (
 	"fmt"
 	"kmt/pkg/config"
-	"os"
-	"strconv"
-	"strings"
 
 	"github.com/fatih/color"
+	gomigrate "github.com/golang-migrate/migrate/v4"
 )
 
 type version struct {
@@ -26,70 +24,41 @@ func NewVersion(config config.Migration) version {
 	}
 }
 
-func (v version) Call(source string, schema string) (uint, int) {
+func (v version) Call(source string, schema string) (uint, int, error) {
 	dbConfig, ok := v.config.Connections[source]
 	if !ok {
-		v.errorColor.Printf("Database connection '%s' not found\n", v.boldFont.Sprint(source))
-
-		return 0, 0
+		return 0, 0, Errorf(ERROR_NOT_FOUND, "database connection '%s' not found", source)
 	}
 
 	_, ok = dbConfig.Schemas[schema]
 	if !ok {
-		v.errorColor.Printf("Schema '%s' not found\n", v.boldFont.Sprint(schema))
-
-		return 0, 0
+		return 0, 0, Errorf(ERROR_NOT_FOUND, "schema '%s' not found", schema)
 	}
 
 	db, err := config.NewConnection(dbConfig)
 	if err != nil {
-		v.errorColor.Println(err.Error())
-
-		return 0, 0
+		return 0, 0, Wrap(ERROR_CONNECTION, err)
 	}
 
-	migrator := config.NewMigrator(db, dbConfig.Name, schema, fmt.Sprintf("%s/%s", v.config.Folder, schema))
-	version, _, err := migrator.Version()
+	migrator, err := config.NewMigrator(db, dbConfig.Name, schema, fmt.Sprintf("%s/%s", v.config.Folder, schema))
 	if err != nil {
-		v.errorColor.Println(err.Error())
+		return 0, 0, Wrap(ERROR_CONNECTION, err)
+	}
 
-		return 0, 0
+	version, _, err := migrator.Version()
+	if err != nil && err != gomigrate.ErrNilVersion {
+		return 0, 0, Wrap(ERROR_MIGRATION, err)
 	}
 
-	files, err := os.ReadDir(fmt.Sprintf("%s/%s", v.config.Folder, schema))
+	i, err := NewIndex(v.config.Folder, schema)
 	if err != nil {
-		v.errorColor.Println(err.Error())
-
-		return 0, 0
+		return 0, 0, err
 	}
 
-	tFiles := len(files)
-	file := strings.Split(files[tFiles-1].Name(), "_")
-	vFile, _ := strconv.Atoi(file[0])
-
-	valid := false
 	number := 0
-	for i, file := range files {
-		if i%2 == 0 {
-			continue
-		}
-
-		f := strings.Split(file.Name(), "_")
-		s, _ := strconv.Atoi(f[0])
-		if !valid && (version == uint(s) || vFile == s) {
-			valid = true
-
-			continue
-		}
-
-		if valid {
-			number++
-		}
-	}
-
-	if version < uint(vFile) {
-		number = number * -1
+	if version < i.Latest() {
+		number = -i.between(version, i.Latest())
 	}
 
-	return version, number
+	return version, number, nil
 }
@@ -3,14 +3,16 @@ package config
 import (
 	"database/sql"
 	"fmt"
-	"log"
+	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 
-	"gopkg.in/yaml.v3"
-
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/lib/pq"
 )
@@ -22,65 +24,405 @@ type (
 	}
 
 	Migration struct {
-		PgDump      string                `yaml:"pg_dump"`
-		Folder      string                `yaml:"folder"`
-		Source      string                `yaml:"source"`
-		Clusters    map[string][]string   `yaml:"clusters"`
-		Connections map[string]Connection `yaml:"connections"`
+		PgDump       string                `yaml:"pg_dump"`
+		PgRestore    string                `yaml:"pg_restore"`
+		Backup       bool                  `yaml:"backup"`
+		BackupFolder string                `yaml:"backup_folder"`
+		Generator    string                `yaml:"generator"`
+		Folder       string                `yaml:"folder"`
+		Source       string                `yaml:"source"`
+		Shadow       string                `yaml:"shadow"`
+		RefuseDrop   bool                  `yaml:"refuse_drop"`
+		LockExpiry   int                   `yaml:"lock_expiry"`
+		LockWait     int                   `yaml:"lock_wait"`
+		Lint         map[string]string     `yaml:"lint"`
+		Clusters     map[string][]string   `yaml:"clusters"`
+		Canaries     map[string]Canary     `yaml:"canaries"`
+		Connections  map[string]Connection `yaml:"connections"`
+	}
+
+	Canary struct {
+		Node  string `yaml:"node"`
+		Batch int    `yaml:"batch"`
+		Query string `yaml:"query"`
 	}
 
 	Connection struct {
-		Host     string                         `yaml:"host"`
-		Port     int                            `yaml:"port"`
-		Name     string                         `yaml:"name"`
-		User     string                         `yaml:"user"`
-		Password string                         `yaml:"password"`
-		Schemas  map[string]map[string][]string `yaml:"schemas"`
+		Dsn                             string                         `yaml:"dsn"`
+		Url                             string                         `yaml:"url"`
+		Host                            string                         `yaml:"host"`
+		Port                            int                            `yaml:"port"`
+		Name                            string                         `yaml:"name"`
+		User                            string                         `yaml:"user"`
+		Password                        string                         `yaml:"password"`
+		SslMode                         string                         `yaml:"sslmode"`
+		SslRootCert                     string                         `yaml:"sslrootcert"`
+		SslCert                         string                         `yaml:"sslcert"`
+		SslKey                          string                         `yaml:"sslkey"`
+		ConnectTimeout                  int                            `yaml:"connect_timeout"`
+		ApplicationName                 string                         `yaml:"application_name"`
+		LockTimeout                     string                         `yaml:"lock_timeout"`
+		StatementTimeout                string                         `yaml:"statement_timeout"`
+		IdleInTransactionSessionTimeout string                         `yaml:"idle_in_transaction_session_timeout"`
+		LockRetries                     *int                           `yaml:"lock_retries"`
+		Protected                       bool                           `yaml:"protected"`
+		Params                          map[string]string              `yaml:"params"`
+		Schemas                         map[string]map[string][]string `yaml:"schemas"`
 	}
 )
 
 func NewConnection(database Connection) (*sql.DB, error) {
-	return sql.Open("postgres", fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", database.Host, database.Port, database.User, database.Password, database.Name))
+	return sql.Open("postgres", database.DataSource())
 }
 
-func NewMigrator(db *sql.DB, database, schema string, path string) *migrate.Migrate {
-	driver, err := postgres.WithInstance(db, &postgres.Config{SchemaName: schema})
+func (c Connection) DataSource() string {
+	return c.conninfo(true)
+}
+
+func (c Connection) Conninfo() string {
+	return c.conninfo(false)
+}
+
+func (c Connection) Secret() string {
+	if c.Password != "" {
+		return c.Password
+	}
+
+	if c.Dsn != "" {
+		for _, p := range dsnParams(c.Dsn) {
+			if p[0] == "password" {
+				return p[1]
+			}
+		}
+	}
+
+	if c.Url != "" {
+		u, err := url.Parse(c.Url)
+		if err != nil {
+			return ""
+		}
+
+		password, ok := u.User.Password()
+		if ok {
+			return password
+		}
+
+		return u.Query().Get("password")
+	}
+
+	return ""
+}
+
+func (c Connection) Database() string {
+	host, port, name := c.Host, strconv.Itoa(c.Port), c.Name
+	if c.Dsn != "" {
+		for _, p := range dsnParams(c.Dsn) {
+			switch p[0] {
+			case "host":
+				host = p[1]
+			case "port":
+				port = p[1]
+			case "dbname":
+				name = p[1]
+			}
+		}
+	}
+
+	if c.Url != "" {
+		u, err := url.Parse(c.Url)
+		if err == nil {
+			host, port, name = u.Hostname(), u.Port(), strings.TrimPrefix(u.Path, "/")
+		}
+	}
+
+	if host == "" {
+		host = "localhost"
+	}
+
+	if port == "" || port == "0" {
+		port = "5432"
+	}
+
+	return fmt.Sprintf("%s:%s/%s", strings.ToLower(host), port, name)
+}
+
+func (c Connection) conninfo(withPassword bool) string {
+	if c.Url != "" {
+		return c.address(withPassword)
+	}
+
+	params := dsnParams(c.Dsn)
+	if c.Dsn == "" {
+		port := ""
+		if c.Port > 0 {
+			port = strconv.Itoa(c.Port)
+		}
+
+		sslMode := c.SslMode
+		if sslMode == "" {
+			sslMode = SSL_MODE_DISABLE
+		}
+
+		params = [][2]string{
+			{"host", c.Host},
+			{"port", port},
+			{"user", c.User},
+			{"dbname", c.Name},
+			{"sslmode", sslMode},
+		}
+	}
+
+	for _, o := range c.options() {
+		params = override(params, o)
+	}
+
+	options := []string{}
+	for _, p := range params {
+		if p[1] == "" || (p[0] == "password" && !withPassword) {
+			continue
+		}
+
+		options = append(options, fmt.Sprintf("%s=%s", p[0], quote(p[1])))
+	}
+
+	return strings.Join(options, " ")
+}
+
+func (c Connection) address(withPassword bool) string {
+	u, err := url.Parse(c.Url)
 	if err != nil {
-		log.Fatalln(err.Error())
+		if withPassword {
+			return c.Url
+		}
+
+		return ""
+	}
+
+	query := u.Query()
+	for _, o := range c.options() {
+		if o[0] == "password" && u.User != nil {
+			u.User = url.UserPassword(u.User.Username(), o[1])
+
+			continue
+		}
+
+		query.Set(o[0], o[1])
+	}
+
+	if !withPassword {
+		if u.User != nil {
+			u.User = url.User(u.User.Username())
+		}
+
+		query.Del("password")
+	}
+
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+func (c Connection) options() [][2]string {
+	params := [][2]string{
+		{"sslmode", c.SslMode},
+		{"sslrootcert", c.SslRootCert},
+		{"sslcert", c.SslCert},
+		{"sslkey", c.SslKey},
+		{"application_name", c.ApplicationName},
+	}
+
+	if c.ConnectTimeout > 0 {
+		params = append(params, [2]string{"connect_timeout", strconv.Itoa(c.ConnectTimeout)})
+	}
+
+	keys := make([]string, 0, len(c.Params))
+	for k := range c.Params {
+		keys = append(keys, k)
 	}
 
-	migrate, err := migrate.NewWithDatabaseInstance(fmt.Sprintf("file://%s", path), database, driver)
+	sort.Strings(keys)
+	for _, k := range keys {
+		params = append(params, [2]string{k, c.Params[k]})
+	}
+
+	if c.Password != "" {
+		params = append(params, [2]string{"password", c.Password})
+	}
+
+	result := [][2]string{}
+	for _, p := range params {
+		if p[1] != "" {
+			result = append(result, p)
+		}
+	}
+
+	return result
+}
+
+func override(params [][2]string, option [2]string) [][2]string {
+	for i, p := range params {
+		if p[0] == option[0] {
+			params[i] = option
+
+			return params
+		}
+	}
+
+	return append(params, option)
+}
+
+func quote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " '\\") {
+		return value
+	}
+
+	return fmt.Sprintf("'%s'", strings.NewReplacer("\\", "\\\\", "'", "\\'").Replace(value))
+}
+
+func dsnParams(dsn string) [][2]string {
+	params := [][2]string{}
+
+	i := 0
+	for i < len(dsn) {
+		for i < len(dsn) && dsn[i] == ' ' {
+			i++
+		}
+
+		start := i
+		for i < len(dsn) && dsn[i] != '=' && dsn[i] != ' ' {
+			i++
+		}
+
+		key := dsn[start:i]
+		for i < len(dsn) && dsn[i] == ' ' {
+			i++
+		}
+
+		if key == "" || i >= len(dsn) || dsn[i] != '=' {
+			break
+		}
+
+		i++
+		for i < len(dsn) && dsn[i] == ' ' {
+			i++
+		}
+
+		var value strings.Builder
+		quoted := i < len(dsn) && dsn[i] == '\''
+		if quoted {
+			i++
+		}
+
+		for i < len(dsn) {
+			c := dsn[i]
+			if c == '\\' && i+1 < len(dsn) {
+				value.WriteByte(dsn[i+1])
+				i += 2
+
+				continue
+			}
+
+			if (quoted && c == '\'') || (!quoted && c == ' ') {
+				i++
+
+				break
+			}
+
+			value.WriteByte(c)
+			i++
+		}
+
+		params = append(params, [2]string{key, value.String()})
+	}
+
+	return params
+}
+
+func NewMigrator(db *sql.DB, database, schema string, path string) (*migrate.Migrate, error) {
+	driver, err := postgres.WithInstance(db, &postgres.Config{SchemaName: schema})
 	if err != nil {
-		log.Fatalln(err.Error())
+		return nil, err
 	}
 
-	return migrate
+	return migrate.NewWithDatabaseInstance(fmt.Sprintf("file://%s", path), database, driver)
 }
 
-func Parse(path string) Config {
+func Parse(path string, profile string) (Config, error) {
 	config := Config{}
-	c, err := os.ReadFile(path)
+	path, err := Find(path)
+	if err != nil {
+		return config, err
+	}
+
+	document, err := load(path)
+	if err != nil {
+		return config, err
+	}
+
+	if profile != "" {
+		overlay, err := load(Overlay(path, profile))
+		if err != nil {
+			return config, err
+		}
+
+		merge(&document, &overlay)
+	}
+
+	err = interpolate(&document)
 	if err != nil {
-		log.Fatalf("Error occur: %s\n", err.Error())
+		return config, fmt.Errorf("%s: %w", path, err)
 	}
 
-	err = yaml.Unmarshal(c, &config)
+	err = Validate(path, &document)
 	if err != nil {
-		log.Fatalln(err.Error())
+		return config, err
+	}
+
+	err = document.Decode(&config)
+	if err != nil {
+		return config, err
 	}
 
 	if config.Migration.PgDump == "" {
 		config.Migration.PgDump = "pg_dump"
 	}
 
+	if config.Migration.PgRestore == "" {
+		config.Migration.PgRestore = filepath.Join(filepath.Dir(config.Migration.PgDump), "pg_restore")
+	}
+
+	if config.Migration.Generator == "" {
+		config.Migration.Generator = GENERATOR_CATALOG
+	}
+
 	if config.Migration.Folder == "" {
 		config.Migration.Folder = "migrations"
 	}
 
+	if !filepath.IsAbs(config.Migration.Folder) {
+		config.Migration.Folder = filepath.Join(filepath.Dir(path), config.Migration.Folder)
+	}
+
+	if config.Migration.BackupFolder == "" {
+		config.Migration.BackupFolder = "backups"
+	}
+
+	if !filepath.IsAbs(config.Migration.BackupFolder) {
+		config.Migration.BackupFolder = filepath.Join(filepath.Dir(path), config.Migration.BackupFolder)
+	}
+
 	if config.Migration.Source == "" {
 		config.Migration.Source = "source"
 	}
 
+	if config.Migration.LockExpiry == 0 {
+		config.Migration.LockExpiry = LOCK_EXPIRY
+	}
+
+	if config.Migration.Lint == nil {
+		config.Migration.Lint = map[string]string{}
+	}
+
 	os.MkdirAll(config.Migration.Folder, 0777)
 
 	for k, cs := range config.Migration.Connections {
@@ -103,5 +445,5 @@ func Parse(path string) Config {
 		}
 	}
 
-	return config
+	return config, nil
 }
@@ -11,4 +11,35 @@ const (
 	REPOSITORY = "https://github.com/suryakoinworks/kw-migrate.git"
 
 	CONFIG_FILE = "Kmtfile.yml"
+
+	SECRET_FILE_PREFIX = "file:"
+
+	SSL_MODE_DISABLE = "disable"
+
+	GENERATOR_CATALOG = "catalog"
+	GENERATOR_PG_DUMP = "pg_dump"
+
+	STRATEGY_ALL    = "all"
+	STRATEGY_CANARY = "canary"
+
+	LOCK_EXPIRY = 1800
+	LOCK_POLL   = time.Second
+
+	LOCK_RETRIES       = 3
+	LOCK_RETRY_BACKOFF = time.Second
+	LOCK_NOT_AVAILABLE = "55P03"
+
+	ANNOTATION_PREFIX         = "kmt:"
+	ANNOTATION_NO_TRANSACTION = "no-transaction"
+	ANNOTATION_AFTER          = "after"
+
+	LINT_INDEX_NOT_CONCURRENT     = "index_not_concurrent"
+	LINT_NOT_NULL_WITHOUT_DEFAULT = "not_null_without_default"
+	LINT_COLUMN_TYPE_CHANGE       = "column_type_change"
+	LINT_DROP_WITHOUT_DOWN        = "drop_without_down"
+	LINT_RENAME                   = "rename"
+
+	LINT_ERROR   = "error"
+	LINT_WARNING = "warning"
+	LINT_OFF     = "off"
 )
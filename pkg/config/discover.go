@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+func Find(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+
+	_, err := os.Stat(CONFIG_FILE)
+	if err == nil {
+		return CONFIG_FILE, nil
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("'%s' not found in current or parent directories", CONFIG_FILE)
+		}
+
+		dir = parent
+
+		candidate := filepath.Join(dir, CONFIG_FILE)
+		_, err = os.Stat(candidate)
+		if err == nil {
+			return candidate, nil
+		}
+	}
+}
+
+func Overlay(path string, profile string) string {
+	extension := filepath.Ext(path)
+
+	return fmt.Sprintf("%s.%s%s", strings.TrimSuffix(path, extension), profile, extension)
+}
+
+func load(path string) (yaml.Node, error) {
+	document := yaml.Node{}
+	c, err := os.ReadFile(path)
+	if err != nil {
+		return document, err
+	}
+
+	err = yaml.Unmarshal(c, &document)
+	if err != nil {
+		return document, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return document, nil
+}
+
+func merge(base *yaml.Node, overlay *yaml.Node) {
+	if base.Kind == yaml.DocumentNode && overlay.Kind == yaml.DocumentNode {
+		if len(base.Content) == 0 {
+			base.Content = overlay.Content
+
+			return
+		}
+
+		if len(overlay.Content) > 0 {
+			merge(base.Content[0], overlay.Content[0])
+		}
+
+		return
+	}
+
+	if base.Kind != yaml.MappingNode || overlay.Kind != yaml.MappingNode {
+		*base = *overlay
+
+		return
+	}
+
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key := overlay.Content[i]
+		value := overlay.Content[i+1]
+
+		found := false
+		for j := 0; j+1 < len(base.Content); j += 2 {
+			if base.Content[j].Value == key.Value {
+				merge(base.Content[j+1], value)
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			base.Content = append(base.Content, key, value)
+		}
+	}
+}
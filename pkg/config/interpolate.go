@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var reference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+func interpolate(document *yaml.Node) error {
+	connections := lookup(lookup(document, "migration"), "connections")
+	if connections == nil || connections.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(connections.Content); i += 2 {
+		name := connections.Content[i].Value
+		connection := connections.Content[i+1]
+		if connection.Kind != yaml.MappingNode {
+			continue
+		}
+
+		err := fields(name, "", connection)
+		if err != nil {
+			return err
+		}
+
+		err = fields(name, "params.", lookup(connection, "params"))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func fields(name string, prefix string, node *yaml.Node) error {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		field := node.Content[i].Value
+		value := node.Content[i+1]
+		if value.Kind != yaml.ScalarNode {
+			continue
+		}
+
+		resolved, err := Resolve(value.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: connection '%s' field '%s%s': %w", value.Line, name, prefix, field, err)
+		}
+
+		if resolved == value.Value {
+			continue
+		}
+
+		value.Value = resolved
+		value.Tag = ""
+		value.Style = 0
+	}
+
+	return nil
+}
+
+func Resolve(value string) (string, error) {
+	var err error
+	result := reference.ReplaceAllStringFunc(value, func(match string) string {
+		parts := reference.FindStringSubmatch(match)
+		env, ok := os.LookupEnv(parts[1])
+		if ok && (env != "" || parts[2] == "") {
+			return env
+		}
+
+		if parts[2] != "" {
+			return parts[3]
+		}
+
+		if err == nil {
+			err = fmt.Errorf("environment variable '%s' is not set", parts[1])
+		}
+
+		return match
+	})
+	if err != nil {
+		return value, err
+	}
+
+	if !strings.HasPrefix(result, SECRET_FILE_PREFIX) {
+		return result, nil
+	}
+
+	path := strings.TrimPrefix(result, SECRET_FILE_PREFIX)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return value, fmt.Errorf("unable to read secret file '%s': %w", path, err)
+	}
+
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+func lookup(node *yaml.Node, key string) *yaml.Node {
+	if node == nil {
+		return nil
+	}
+
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil
+		}
+
+		node = node.Content[0]
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
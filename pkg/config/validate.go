@@ -0,0 +1,318 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	Problem struct {
+		Line    int
+		Column  int
+		Message string
+	}
+
+	ValidationError struct {
+		Path     string
+		Problems []Problem
+	}
+
+	validator struct {
+		problems []Problem
+	}
+)
+
+var (
+	rootKeys       = []string{"version", "migration"}
+	migrationKeys  = []string{"pg_dump", "pg_restore", "backup", "backup_folder", "generator", "folder", "source", "shadow", "refuse_drop", "lock_expiry", "lock_wait", "lint", "clusters", "canaries", "connections"}
+	canaryKeys     = []string{"node", "batch", "query"}
+	connectionKeys = []string{"dsn", "url", "host", "port", "name", "user", "password", "sslmode", "sslrootcert", "sslcert", "sslkey", "connect_timeout", "application_name", "lock_timeout", "statement_timeout", "idle_in_transaction_session_timeout", "lock_retries", "protected", "params", "schemas"}
+	schemaKeys     = []string{"excludes", "with_data"}
+	generators     = []string{GENERATOR_CATALOG, GENERATOR_PG_DUMP}
+	sslModes       = []string{SSL_MODE_DISABLE, "allow", "prefer", "require", "verify-ca", "verify-full"}
+	lintRules      = []string{LINT_INDEX_NOT_CONCURRENT, LINT_NOT_NULL_WITHOUT_DEFAULT, LINT_COLUMN_TYPE_CHANGE, LINT_DROP_WITHOUT_DOWN, LINT_RENAME}
+	lintSeverities = []string{LINT_ERROR, LINT_WARNING, LINT_OFF}
+)
+
+func (e ValidationError) Error() string {
+	messages := []string{fmt.Sprintf("invalid config %s:", e.Path)}
+	for _, p := range e.Problems {
+		if p.Line == 0 {
+			messages = append(messages, fmt.Sprintf("  - %s", p.Message))
+
+			continue
+		}
+
+		if p.Column == 0 {
+			messages = append(messages, fmt.Sprintf("  - line %d: %s", p.Line, p.Message))
+
+			continue
+		}
+
+		messages = append(messages, fmt.Sprintf("  - line %d column %d: %s", p.Line, p.Column, p.Message))
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+func Validate(path string, document *yaml.Node) error {
+	v := validator{problems: []Problem{}}
+
+	root := document
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+
+	if root.Kind != yaml.MappingNode {
+		v.report(root, "config must be a mapping")
+	} else {
+		v.keys(root, "", rootKeys)
+		v.migration(lookup(root, "migration"))
+	}
+
+	err := document.Decode(&Config{})
+
+	var typeError *yaml.TypeError
+	if errors.As(err, &typeError) {
+		for _, m := range typeError.Errors {
+			p := Problem{Message: m}
+			if strings.HasPrefix(m, "line ") {
+				parts := strings.SplitN(strings.TrimPrefix(m, "line "), ": ", 2)
+				line, err := strconv.Atoi(parts[0])
+				if err == nil && len(parts) == 2 {
+					p = Problem{Line: line, Message: parts[1]}
+				}
+			}
+
+			v.problems = append(v.problems, p)
+		}
+	} else if err != nil {
+		v.problems = append(v.problems, Problem{Message: err.Error()})
+	}
+
+	if len(v.problems) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		if v.problems[i].Line != v.problems[j].Line {
+			return v.problems[i].Line < v.problems[j].Line
+		}
+
+		return v.problems[i].Column < v.problems[j].Column
+	})
+
+	return ValidationError{Path: path, Problems: v.problems}
+}
+
+func (v *validator) migration(node *yaml.Node) {
+	if node == nil {
+		v.problems = append(v.problems, Problem{Message: "'migration' is required"})
+
+		return
+	}
+
+	if !v.mapping(node, "migration") {
+		return
+	}
+
+	v.keys(node, "migration.", migrationKeys)
+
+	v.minimum(lookup(node, "lock_expiry"), "lock_expiry", 0)
+	v.minimum(lookup(node, "lock_wait"), "lock_wait", 0)
+
+	generator := lookup(node, "generator")
+	if generator != nil && !contains(generators, generator.Value) {
+		v.report(generator, fmt.Sprintf("generator must be one of %s", strings.Join(generators, ", ")))
+	}
+
+	lint := lookup(node, "lint")
+	if lint != nil && v.mapping(lint, "migration.lint") {
+		v.keys(lint, "migration.lint.", lintRules)
+		for i := 0; i+1 < len(lint.Content); i += 2 {
+			if !contains(lintSeverities, lint.Content[i+1].Value) {
+				v.report(lint.Content[i+1], fmt.Sprintf("lint rule '%s' must be one of %s", lint.Content[i].Value, strings.Join(lintSeverities, ", ")))
+			}
+		}
+	}
+
+	connections := lookup(node, "connections")
+	names := []string{}
+	if connections == nil {
+		v.report(node, "'migration.connections' is required")
+	} else if v.mapping(connections, "migration.connections") {
+		for i := 0; i+1 < len(connections.Content); i += 2 {
+			names = append(names, connections.Content[i].Value)
+			v.connection(connections.Content[i].Value, connections.Content[i+1])
+		}
+	}
+
+	for _, k := range []string{"source", "shadow"} {
+		value := lookup(node, k)
+		if value != nil && value.Value != "" && !contains(names, value.Value) {
+			v.report(value, fmt.Sprintf("%s connection '%s' isn't defined", k, value.Value))
+		}
+	}
+
+	members := map[string][]string{}
+	clusters := lookup(node, "clusters")
+	if clusters != nil && v.mapping(clusters, "migration.clusters") {
+		for i := 0; i+1 < len(clusters.Content); i += 2 {
+			name := clusters.Content[i].Value
+			list := clusters.Content[i+1]
+			if list.Kind != yaml.SequenceNode {
+				v.report(list, fmt.Sprintf("cluster '%s' must be a list of connections", name))
+
+				continue
+			}
+
+			members[name] = []string{}
+			for _, c := range list.Content {
+				members[name] = append(members[name], c.Value)
+				if !contains(names, c.Value) {
+					v.report(c, fmt.Sprintf("cluster '%s' connection '%s' isn't defined", name, c.Value))
+				}
+			}
+		}
+	}
+
+	canaries := lookup(node, "canaries")
+	if canaries != nil && v.mapping(canaries, "migration.canaries") {
+		for i := 0; i+1 < len(canaries.Content); i += 2 {
+			name := canaries.Content[i].Value
+			canary := canaries.Content[i+1]
+
+			_, ok := members[name]
+			if !ok {
+				v.report(canaries.Content[i], fmt.Sprintf("canary cluster '%s' isn't defined", name))
+			}
+
+			if !v.mapping(canary, fmt.Sprintf("migration.canaries.%s", name)) {
+				continue
+			}
+
+			v.keys(canary, fmt.Sprintf("migration.canaries.%s.", name), canaryKeys)
+
+			n := lookup(canary, "node")
+			if n != nil && ok && !contains(members[name], n.Value) {
+				v.report(n, fmt.Sprintf("canary node '%s' isn't a node of cluster '%s'", n.Value, name))
+			}
+
+			v.minimum(lookup(canary, "batch"), "batch", 0)
+		}
+	}
+}
+
+func (v *validator) connection(name string, node *yaml.Node) {
+	prefix := fmt.Sprintf("migration.connections.%s", name)
+	if !v.mapping(node, prefix) {
+		return
+	}
+
+	v.keys(node, prefix+".", connectionKeys)
+
+	dsn := lookup(node, "dsn")
+	url := lookup(node, "url")
+	if dsn != nil && url != nil {
+		v.report(url, fmt.Sprintf("connection '%s' can't have both dsn and url", name))
+	}
+
+	if dsn == nil && url == nil {
+		for _, k := range []string{"host", "name"} {
+			value := lookup(node, k)
+			if value == nil || value.Value == "" {
+				v.report(node, fmt.Sprintf("connection '%s' %s is required", name, k))
+			}
+		}
+
+		port := lookup(node, "port")
+		if port == nil {
+			v.report(node, fmt.Sprintf("connection '%s' port is required", name))
+		} else {
+			n, err := strconv.Atoi(port.Value)
+			if err == nil && (n < 1 || n > 65535) {
+				v.report(port, fmt.Sprintf("connection '%s' port must be between 1 and 65535", name))
+			}
+		}
+	}
+
+	sslMode := lookup(node, "sslmode")
+	if sslMode != nil && !contains(sslModes, sslMode.Value) {
+		v.report(sslMode, fmt.Sprintf("sslmode must be one of %s", strings.Join(sslModes, ", ")))
+	}
+
+	v.minimum(lookup(node, "connect_timeout"), "connect_timeout", 0)
+	v.minimum(lookup(node, "lock_retries"), "lock_retries", 0)
+
+	schemas := lookup(node, "schemas")
+	if schemas == nil || !v.mapping(schemas, prefix+".schemas") {
+		return
+	}
+
+	for i := 0; i+1 < len(schemas.Content); i += 2 {
+		schema := schemas.Content[i+1]
+		if schema.Kind == yaml.ScalarNode && schema.Tag == "!!null" {
+			continue
+		}
+
+		path := fmt.Sprintf("%s.schemas.%s", prefix, schemas.Content[i].Value)
+		if v.mapping(schema, path) {
+			v.keys(schema, path+".", schemaKeys)
+		}
+	}
+}
+
+func (v *validator) keys(node *yaml.Node, prefix string, allowed []string) {
+	seen := map[string]bool{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if seen[key.Value] {
+			v.report(key, fmt.Sprintf("duplicate key '%s%s'", prefix, key.Value))
+		}
+
+		seen[key.Value] = true
+		if !contains(allowed, key.Value) {
+			v.report(key, fmt.Sprintf("unknown key '%s%s'", prefix, key.Value))
+		}
+	}
+}
+
+func (v *validator) mapping(node *yaml.Node, path string) bool {
+	if node.Kind == yaml.MappingNode {
+		return true
+	}
+
+	v.report(node, fmt.Sprintf("'%s' must be a mapping", path))
+
+	return false
+}
+
+func (v *validator) minimum(node *yaml.Node, name string, min int) {
+	if node == nil {
+		return
+	}
+
+	n, err := strconv.Atoi(node.Value)
+	if err == nil && n < min {
+		v.report(node, fmt.Sprintf("%s must be greater than or equal to %d", name, min))
+	}
+}
+
+func (v *validator) report(node *yaml.Node, message string) {
+	v.problems = append(v.problems, Problem{Line: node.Line, Column: node.Column, Message: message})
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,366 @@
+package db
+
+import (
+	"fmt"
+	"kmt/pkg/config"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var alterPriorities = map[string]int{
+	OBJECT_ENUM:              0,
+	OBJECT_TABLE:             1,
+	OBJECT_COLUMN:            2,
+	OBJECT_CONSTRAINT:        3,
+	OBJECT_INDEX:             4,
+	OBJECT_FUNCTION:          5,
+	OBJECT_VIEW:              6,
+	OBJECT_MATERIALIZED_VIEW: 7,
+}
+
+var (
+	indexTable   = regexp.MustCompile(`(?i)\sON\s+(?:ONLY\s+)?(\S+)`)
+	nextSequence = regexp.MustCompile(`^nextval\('([^']+)'(?:::regclass)?\)$`)
+)
+
+func (s Snapshot) Alter(target Snapshot) Migration {
+	differences := s.Compare(target)
+	sort.SliceStable(differences, func(i, j int) bool {
+		return s.priority(differences[i]) < s.priority(differences[j])
+	})
+
+	up := []string{}
+	down := []string{}
+	transaction := true
+	for _, d := range differences {
+		u, r := s.alter(target, d)
+		if d.Kind == OBJECT_ENUM {
+			for _, v := range u {
+				if strings.Contains(v, " ADD VALUE ") {
+					transaction = false
+				}
+			}
+		}
+
+		table := s.table(d)
+		if table != "" {
+			_, ok := target.Tables[table]
+			if !ok {
+				r = []string{}
+			}
+
+			_, ok = s.Tables[table]
+			if !ok {
+				u = []string{}
+			}
+		}
+
+		up = append(up, u...)
+		down = append(r, down...)
+	}
+
+	if !transaction {
+		up = append([]string{fmt.Sprintf("-- %s%s", config.ANNOTATION_PREFIX, config.ANNOTATION_NO_TRANSACTION)}, up...)
+	}
+
+	return Migration{
+		Name:       s.Schema,
+		UpScript:   strings.Join(up, "\n"),
+		DownScript: strings.Join(down, "\n"),
+	}
+}
+
+func (s Snapshot) priority(d Difference) int {
+	if d.Source == "" {
+		return -1 - alterPriorities[d.Kind]
+	}
+
+	return alterPriorities[d.Kind]
+}
+
+func (s Snapshot) table(d Difference) string {
+	if d.Kind == OBJECT_CONSTRAINT {
+		return d.Table
+	}
+
+	definition := d.Source
+	if definition == "" {
+		definition = d.Target
+	}
+
+	m := indexTable.FindStringSubmatch(definition)
+	if d.Kind != OBJECT_INDEX || m == nil {
+		return ""
+	}
+
+	return strings.TrimPrefix(m[1], fmt.Sprintf("%s.", s.Schema))
+}
+
+func (s Snapshot) alter(target Snapshot, d Difference) ([]string, []string) {
+	switch d.Kind {
+	case OBJECT_TABLE:
+		name := s.qualify(d.Name)
+		if d.Target == "" {
+			return s.createTable(name, s.Tables[d.Name])
+		}
+
+		down, up := s.createTable(name, target.Tables[d.Name])
+
+		return up, down
+	case OBJECT_COLUMN:
+		return s.alterColumn(target, d)
+	case OBJECT_CONSTRAINT:
+		name := s.qualify(d.Table)
+		up := []string{}
+		down := []string{}
+		if d.Target != "" {
+			up = append(up, fmt.Sprintf(SECURE_DROP_CONSTRAINT, name, d.Name))
+			down = append(down, fmt.Sprintf(SQL_ADD_CONSTRAINT, name, d.Name, d.Target))
+		}
+
+		if d.Source != "" {
+			up = append(up, fmt.Sprintf(SQL_ADD_CONSTRAINT, name, d.Name, d.Source))
+			down = append([]string{fmt.Sprintf(SECURE_DROP_CONSTRAINT, name, d.Name)}, down...)
+		}
+
+		return up, down
+	case OBJECT_INDEX:
+		up := []string{}
+		down := []string{}
+		if d.Target != "" {
+			up = append(up, fmt.Sprintf(SECURE_DROP_INDEX, d.Name))
+			down = append(down, fmt.Sprintf("%s;", s.secureIndex(d.Target)))
+		}
+
+		if d.Source != "" {
+			up = append(up, fmt.Sprintf("%s;", s.secureIndex(d.Source)))
+			down = append([]string{fmt.Sprintf(SECURE_DROP_INDEX, d.Name)}, down...)
+		}
+
+		return up, down
+	case OBJECT_ENUM:
+		return s.alterEnum(target, d)
+	case OBJECT_FUNCTION:
+		name := s.qualify(d.Name)
+		if d.Source == "" {
+			return []string{fmt.Sprintf("DROP FUNCTION IF EXISTS %s;", name)}, []string{fmt.Sprintf("%s;", d.Target)}
+		}
+
+		if d.Target == "" {
+			return []string{fmt.Sprintf("%s;", d.Source)}, []string{fmt.Sprintf("DROP FUNCTION IF EXISTS %s;", name)}
+		}
+
+		return []string{fmt.Sprintf("%s;", d.Source)}, []string{fmt.Sprintf("%s;", d.Target)}
+	case OBJECT_VIEW:
+		name := s.qualify(d.Name)
+		if d.Source == "" {
+			return []string{fmt.Sprintf(SECURE_DROP_VIEW, name)}, []string{fmt.Sprintf(SECURE_CREATE_VIEW, name, d.Target)}
+		}
+
+		if d.Target == "" {
+			return []string{fmt.Sprintf(SECURE_CREATE_VIEW, name, d.Source)}, []string{fmt.Sprintf(SECURE_DROP_VIEW, name)}
+		}
+
+		return []string{fmt.Sprintf(SECURE_CREATE_VIEW, name, d.Source)}, []string{fmt.Sprintf(SECURE_CREATE_VIEW, name, d.Target)}
+	case OBJECT_MATERIALIZED_VIEW:
+		name := s.qualify(d.Name)
+		up := []string{}
+		down := []string{}
+		if d.Target != "" {
+			up = append(up, fmt.Sprintf(SECURE_DROP_MATERIALIZED_VIEW, name))
+			down = append(down, fmt.Sprintf(SECURE_CREATE_MATERIALIZED_VIEW, name, d.Target))
+		}
+
+		if d.Source != "" {
+			up = append(up, fmt.Sprintf(SECURE_CREATE_MATERIALIZED_VIEW, name, d.Source))
+			down = append([]string{fmt.Sprintf(SECURE_DROP_MATERIALIZED_VIEW, name)}, down...)
+		}
+
+		return up, down
+	}
+
+	return []string{}, []string{}
+}
+
+func (s Snapshot) alterColumn(target Snapshot, d Difference) ([]string, []string) {
+	name := s.qualify(d.Table)
+	if d.Target == "" {
+		return s.addColumn(name, s.column(d.Table, d.Name))
+	}
+
+	if d.Source == "" {
+		down, up := s.addColumn(name, target.column(d.Table, d.Name))
+
+		return up, down
+	}
+
+	source := s.column(d.Table, d.Name)
+	compare := target.column(d.Table, d.Name)
+
+	up := []string{}
+	down := []string{}
+	if source.Default != compare.Default && compare.Default != "" {
+		up = append(up, fmt.Sprintf(SQL_ALTER_COLUMN_DROP_DEFAULT, name, d.Name))
+	}
+
+	if source.Type != compare.Type {
+		up = append(up, fmt.Sprintf(SQL_ALTER_COLUMN_TYPE, name, d.Name, source.Type, d.Name, source.Type))
+		down = append(down, fmt.Sprintf(SQL_ALTER_COLUMN_TYPE, name, d.Name, compare.Type, d.Name, compare.Type))
+	}
+
+	if source.Default != compare.Default {
+		if source.Default != "" {
+			sequence := sequenceOf(source)
+			if sequence != "" {
+				up = append(up, fmt.Sprintf("%s %s;", SECURE_CREATE_SEQUENCE, sequence))
+			}
+
+			up = append(up, fmt.Sprintf(SQL_ALTER_COLUMN_SET_DEFAULT, name, d.Name, source.Default))
+			down = append([]string{fmt.Sprintf(SQL_ALTER_COLUMN_DROP_DEFAULT, name, d.Name)}, down...)
+		}
+
+		if compare.Default != "" {
+			down = append(down, fmt.Sprintf(SQL_ALTER_COLUMN_SET_DEFAULT, name, d.Name, compare.Default))
+		}
+	}
+
+	if source.NotNull != compare.NotNull {
+		if source.NotNull {
+			up = append(up, fmt.Sprintf(SQL_ALTER_COLUMN_SET_NOT_NULL, name, d.Name))
+			down = append(down, fmt.Sprintf(SQL_ALTER_COLUMN_DROP_NOT_NULL, name, d.Name))
+		} else {
+			up = append(up, fmt.Sprintf(SQL_ALTER_COLUMN_DROP_NOT_NULL, name, d.Name))
+			down = append(down, fmt.Sprintf(SQL_ALTER_COLUMN_SET_NOT_NULL, name, d.Name))
+		}
+	}
+
+	return up, down
+}
+
+func (s Snapshot) alterEnum(target Snapshot, d Difference) ([]string, []string) {
+	if d.Target == "" {
+		return []string{enum{}.createDdl(d.Name, strings.Join(s.Enums[d.Name], "#"))}, []string{fmt.Sprintf(SECURE_DROP_TYPE, d.Name)}
+	}
+
+	if d.Source == "" {
+		return []string{fmt.Sprintf(SECURE_DROP_TYPE, d.Name)}, []string{enum{}.createDdl(d.Name, strings.Join(target.Enums[d.Name], "#"))}
+	}
+
+	existing := map[string]bool{}
+	for _, v := range target.Enums[d.Name] {
+		existing[v] = true
+	}
+
+	values := s.Enums[d.Name]
+	first := 0
+	for i, v := range values {
+		if existing[v] {
+			first = i
+
+			break
+		}
+	}
+
+	up := []string{}
+	down := []string{}
+	for i := first - 1; i >= 0; i-- {
+		up = append(up, fmt.Sprintf(SQL_ADD_ENUM_VALUE_BEFORE, d.Name, values[i], values[i+1]))
+		down = append(down, fmt.Sprintf(SQL_REMOVE_ENUM_VALUE, values[i], d.Name))
+	}
+
+	for i, v := range values {
+		if existing[v] {
+			delete(existing, v)
+
+			continue
+		}
+
+		if i < first {
+			continue
+		}
+
+		if i > 0 {
+			up = append(up, fmt.Sprintf(SQL_ADD_ENUM_VALUE_AFTER, d.Name, v, values[i-1]))
+		} else {
+			up = append(up, fmt.Sprintf(SQL_ADD_ENUM_VALUE, d.Name, v))
+		}
+
+		down = append(down, fmt.Sprintf(SQL_REMOVE_ENUM_VALUE, v, d.Name))
+	}
+
+	for _, v := range target.Enums[d.Name] {
+		if existing[v] {
+			up = append(up, fmt.Sprintf(SQL_REMOVE_ENUM_VALUE, v, d.Name))
+		}
+	}
+
+	return up, down
+}
+
+func (s Snapshot) createTable(name string, columns []Column) ([]string, []string) {
+	up := []string{}
+	down := []string{fmt.Sprintf(SECURE_DROP_TABLE, name)}
+	owned := []string{}
+	for _, c := range columns {
+		sequence := sequenceOf(c)
+		if sequence == "" {
+			continue
+		}
+
+		up = append(up, fmt.Sprintf("%s %s;", SECURE_CREATE_SEQUENCE, sequence))
+		owned = append(owned, fmt.Sprintf(SQL_SEQUENCE_OWNED_BY, sequence, name, c.Name))
+		down = append(down, fmt.Sprintf(SECURE_DROP_SEQUENCE, sequence))
+	}
+
+	up = append(up, createTable(name, columns))
+
+	return append(up, owned...), down
+}
+
+func (s Snapshot) addColumn(name string, column Column) ([]string, []string) {
+	up := []string{}
+	down := []string{fmt.Sprintf(SQL_DROP_COLUMN, name, column.Name)}
+
+	sequence := sequenceOf(column)
+	if sequence != "" {
+		up = append(up, fmt.Sprintf("%s %s;", SECURE_CREATE_SEQUENCE, sequence))
+	}
+
+	up = append(up, fmt.Sprintf(SQL_ADD_COLUMN, name, column.Name, column.String()))
+	if sequence != "" {
+		up = append(up, fmt.Sprintf(SQL_SEQUENCE_OWNED_BY, sequence, name, column.Name))
+		down = append(down, fmt.Sprintf(SECURE_DROP_SEQUENCE, sequence))
+	}
+
+	return up, down
+}
+
+func (s Snapshot) column(table string, name string) Column {
+	for _, c := range s.Tables[table] {
+		if c.Name == name {
+			return c
+		}
+	}
+
+	return Column{}
+}
+
+func sequenceOf(column Column) string {
+	m := nextSequence.FindStringSubmatch(column.Default)
+	if m == nil {
+		return ""
+	}
+
+	return m[1]
+}
+
+func (s Snapshot) qualify(name string) string {
+	return fmt.Sprintf("%s.%s", s.Schema, name)
+}
+
+func (Snapshot) secureIndex(definition string) string {
+	definition = strings.Replace(definition, CREATE_UNIQUE_INDEX, SECURE_CREATE_UNIQUE_INDEX, 1)
+
+	return strings.Replace(definition, CREATE_INDEX, SECURE_CREATE_INDEX, 1)
+}
@@ -0,0 +1,181 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type (
+	dependency struct {
+		db *sql.DB
+	}
+
+	Object struct {
+		Kind       string
+		Name       string
+		Total      int
+		Dependents []string
+	}
+)
+
+var priorities = map[string]int{
+	OBJECT_ENUM:              0,
+	OBJECT_TABLE:             1,
+	OBJECT_FUNCTION:          2,
+	OBJECT_VIEW:              3,
+	OBJECT_MATERIALIZED_VIEW: 4,
+}
+
+func NewDependency(db *sql.DB) dependency {
+	return dependency{db: db}
+}
+
+func (o Object) Key() string {
+	return ObjectKey(o.Kind, o.Name)
+}
+
+func ObjectKey(kind string, name string) string {
+	return fmt.Sprintf("%s/%s", kind, name)
+}
+
+func (d dependency) Sort(schema string, excludes ...string) ([]Object, []string, error) {
+	skip := map[string]bool{}
+	for _, v := range excludes {
+		skip[ObjectKey(OBJECT_TABLE, v)] = true
+	}
+
+	objects := map[string]Object{}
+	rows, err := d.db.Query(fmt.Sprintf(QUERY_LIST_OBJECT, schema))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for rows.Next() {
+		object := Object{}
+		err = rows.Scan(&object.Kind, &object.Name, &object.Total)
+		if err != nil {
+			rows.Close()
+
+			return nil, nil, err
+		}
+
+		object.Name = d.shortName(object.Kind, object.Name)
+		if skip[object.Key()] {
+			continue
+		}
+
+		objects[object.Key()] = object
+	}
+
+	rows.Close()
+
+	edges := map[string][]string{}
+	degrees := map[string]int{}
+	rows, err = d.db.Query(fmt.Sprintf(QUERY_LIST_DEPENDENCY, schema))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for rows.Next() {
+		var kind, name, referencedKind, referencedName string
+		err = rows.Scan(&kind, &name, &referencedKind, &referencedName)
+		if err != nil {
+			rows.Close()
+
+			return nil, nil, err
+		}
+
+		object := ObjectKey(kind, d.shortName(kind, name))
+		referenced := ObjectKey(referencedKind, d.shortName(referencedKind, referencedName))
+
+		_, ok := objects[object]
+		if !ok {
+			continue
+		}
+
+		_, ok = objects[referenced]
+		if !ok {
+			continue
+		}
+
+		edges[referenced] = append(edges[referenced], object)
+		degrees[object]++
+	}
+
+	rows.Close()
+
+	for k, v := range edges {
+		object := objects[k]
+		object.Dependents = v
+		objects[k] = object
+	}
+
+	result, cycles := d.sort(objects, edges, degrees)
+
+	return result, cycles, nil
+}
+
+func (d dependency) sort(objects map[string]Object, edges map[string][]string, degrees map[string]int) ([]Object, []string) {
+	queue := []Object{}
+	for k, v := range objects {
+		if degrees[k] == 0 {
+			queue = append(queue, v)
+		}
+	}
+
+	result := []Object{}
+	for len(queue) > 0 {
+		ordered(queue)
+
+		object := queue[0]
+		queue = queue[1:]
+		result = append(result, object)
+
+		for _, v := range edges[object.Key()] {
+			degrees[v]--
+			if degrees[v] == 0 {
+				queue = append(queue, objects[v])
+			}
+		}
+	}
+
+	if len(result) == len(objects) {
+		return result, nil
+	}
+
+	cycles := []string{}
+	for k := range objects {
+		if degrees[k] > 0 {
+			cycles = append(cycles, k)
+		}
+	}
+
+	sort.Strings(cycles)
+
+	return result, cycles
+}
+
+func ordered(objects []Object) {
+	sort.Slice(objects, func(i, j int) bool {
+		if priorities[objects[i].Kind] != priorities[objects[j].Kind] {
+			return priorities[objects[i].Kind] < priorities[objects[j].Kind]
+		}
+
+		return objects[i].Name < objects[j].Name
+	})
+}
+
+func (dependency) shortName(kind string, name string) string {
+	if kind != OBJECT_ENUM {
+		return name
+	}
+
+	sName := strings.Split(name, ".")
+	if len(sName) == 2 {
+		return sName[1]
+	}
+
+	return name
+}
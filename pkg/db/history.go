@@ -0,0 +1,126 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type (
+	history struct {
+		db     *sql.DB
+		schema string
+	}
+
+	History struct {
+		Version    uint
+		Direction  string
+		File       string
+		Checksum   string
+		User       string
+		Host       string
+		StartedAt  time.Time
+		FinishedAt time.Time
+		Success    bool
+		Message    string
+	}
+)
+
+func NewHistory(db *sql.DB, schema string) history {
+	return history{db: db, schema: schema}
+}
+
+func (h history) Record(record History) error {
+	_, err := h.db.Exec(fmt.Sprintf(SQL_CREATE_HISTORY, h.schema))
+	if err != nil {
+		return err
+	}
+
+	_, err = h.db.Exec(
+		fmt.Sprintf(SQL_INSERT_HISTORY, h.schema),
+		int64(record.Version),
+		record.Direction,
+		record.File,
+		record.Checksum,
+		record.User,
+		record.Host,
+		record.StartedAt,
+		record.FinishedAt,
+		record.Success,
+		record.Message,
+	)
+
+	return err
+}
+
+func (h history) List() ([]History, error) {
+	histories := []History{}
+
+	var exists bool
+	err := h.db.QueryRow(QUERY_HISTORY_EXISTS, fmt.Sprintf("%s.kmt_histories", h.schema)).Scan(&exists)
+	if err != nil || !exists {
+		return histories, err
+	}
+
+	rows, err := h.db.Query(fmt.Sprintf(QUERY_LIST_HISTORY, h.schema))
+	if err != nil {
+		return histories, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int64
+		record := History{}
+		err = rows.Scan(
+			&version,
+			&record.Direction,
+			&record.File,
+			&record.Checksum,
+			&record.User,
+			&record.Host,
+			&record.StartedAt,
+			&record.FinishedAt,
+			&record.Success,
+			&record.Message,
+		)
+		if err != nil {
+			return histories, err
+		}
+
+		record.Version = uint(version)
+		histories = append(histories, record)
+	}
+
+	return histories, rows.Err()
+}
+
+func (h history) Checksums(direction string) (map[string]string, error) {
+	checksums := map[string]string{}
+
+	var exists bool
+	err := h.db.QueryRow(QUERY_HISTORY_EXISTS, fmt.Sprintf("%s.kmt_histories", h.schema)).Scan(&exists)
+	if err != nil || !exists {
+		return checksums, err
+	}
+
+	rows, err := h.db.Query(fmt.Sprintf(QUERY_LIST_REPEATABLE, h.schema), direction)
+	if err != nil {
+		return checksums, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var file string
+		var checksum string
+		err = rows.Scan(&file, &checksum)
+		if err != nil {
+			return checksums, err
+		}
+
+		checksums[file] = checksum
+	}
+
+	return checksums, rows.Err()
+}
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type (
+	lock struct {
+		db *sql.DB
+	}
+
+	Lock struct {
+		Target     string
+		Schema     string
+		Nodes      []string
+		Owner      string
+		AcquiredAt time.Time
+		ExpiresAt  time.Time
+		Expired    bool
+	}
+)
+
+func NewLock(db *sql.DB) lock {
+	return lock{db: db}
+}
+
+func (l lock) Acquire(target string, schema string, nodes []string, token string, owner string, expiry time.Duration) (Lock, bool, error) {
+	holder := Lock{}
+
+	_, err := l.db.Exec(SQL_CREATE_LOCK_SCHEMA)
+	if err != nil {
+		return holder, false, err
+	}
+
+	_, err = l.db.Exec(SQL_CREATE_LOCK)
+	if err != nil {
+		return holder, false, err
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return holder, false, err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.Exec(SQL_LOCK_LOCK_TABLE)
+	if err != nil {
+		return holder, false, err
+	}
+
+	_, err = tx.Exec(SQL_CLEAR_EXPIRED_LOCK)
+	if err != nil {
+		return holder, false, err
+	}
+
+	err = tx.QueryRow(QUERY_CONFLICT_LOCK, schema, pq.Array(nodes)).Scan(&holder.Target, &holder.Schema, pq.Array(&holder.Nodes), &holder.Owner, &holder.AcquiredAt, &holder.ExpiresAt, &holder.Expired)
+	if err == nil {
+		return holder, false, nil
+	}
+
+	if err != sql.ErrNoRows {
+		return holder, false, err
+	}
+
+	_, err = tx.Exec(SQL_ACQUIRE_LOCK, target, schema, pq.Array(nodes), token, owner, expiry.Seconds())
+	if err != nil {
+		return holder, false, err
+	}
+
+	return holder, true, tx.Commit()
+}
+
+func (l lock) Refresh(target string, schema string, token string, expiry time.Duration) (bool, error) {
+	result, err := l.db.Exec(SQL_REFRESH_LOCK, target, schema, token, expiry.Seconds())
+	if err != nil {
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+
+	return n > 0, err
+}
+
+func (l lock) Release(target string, schema string, token string) error {
+	_, err := l.db.Exec(SQL_RELEASE_LOCK, target, schema, token)
+
+	return err
+}
+
+func (l lock) ForceRelease(target string, schema string) (bool, error) {
+	exists, err := l.exists()
+	if err != nil || !exists {
+		return false, err
+	}
+
+	result, err := l.db.Exec(SQL_FORCE_RELEASE_LOCK, target, schema)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+
+	return n > 0, err
+}
+
+func (l lock) List() ([]Lock, error) {
+	locks := []Lock{}
+
+	exists, err := l.exists()
+	if err != nil || !exists {
+		return locks, err
+	}
+
+	rows, err := l.db.Query(QUERY_LIST_LOCK)
+	if err != nil {
+		return locks, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		record := Lock{}
+		err = rows.Scan(&record.Target, &record.Schema, pq.Array(&record.Nodes), &record.Owner, &record.AcquiredAt, &record.ExpiresAt, &record.Expired)
+		if err != nil {
+			return locks, err
+		}
+
+		locks = append(locks, record)
+	}
+
+	return locks, rows.Err()
+}
+
+func (l lock) exists() (bool, error) {
+	var exists bool
+	err := l.db.QueryRow(QUERY_HISTORY_EXISTS, LOCK_TABLE).Scan(&exists)
+
+	return exists, err
+}
@@ -36,7 +36,7 @@ func (s materialized) GenerateDdl(schema string) <-chan Migration {
 			channel <- Migration{
 				Name:       name,
 				UpScript:   fmt.Sprintf(SECURE_CREATE_MATERIALIZED_VIEW, name, definition),
-				DownScript: fmt.Sprintf(SECURE_DROP_VIEW, name),
+				DownScript: fmt.Sprintf(SECURE_DROP_MATERIALIZED_VIEW, name),
 			}
 		}
 
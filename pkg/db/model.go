@@ -13,6 +13,18 @@ type (
 )
 
 const (
+	HISTORY_TABLE = "kmt_histories"
+	LOCK_TABLE    = "kmt.kmt_locks"
+
+	OBJECT_ENUM              = "enum"
+	OBJECT_TABLE             = "table"
+	OBJECT_FUNCTION          = "function"
+	OBJECT_VIEW              = "view"
+	OBJECT_MATERIALIZED_VIEW = "materialized_view"
+	OBJECT_COLUMN            = "column"
+	OBJECT_INDEX             = "index"
+	OBJECT_CONSTRAINT        = "constraint"
+
 	ALTER_TABLE = "ALTER TABLE ONLY"
 
 	ADD_CONSTRAINT = "ADD CONSTRAINT"
@@ -33,16 +45,58 @@ const (
 
 	SECURE_CREATE_INDEX = "CREATE INDEX IF NOT EXISTS"
 
+	CREATE_UNIQUE_INDEX = "CREATE UNIQUE INDEX"
+
+	SECURE_CREATE_UNIQUE_INDEX = "CREATE UNIQUE INDEX IF NOT EXISTS"
+
 	SECURE_CREATE_VIEW = "CREATE OR REPLACE VIEW %s AS %s"
 
 	SECURE_CREATE_MATERIALIZED_VIEW = "CREATE MATERIALIZED VIEW IF NOT EXISTS %s AS %s"
 
 	SECURE_DROP_VIEW = "DROP VIEW IF EXISTS %s;"
 
+	SECURE_DROP_MATERIALIZED_VIEW = "DROP MATERIALIZED VIEW IF EXISTS %s;"
+
+	SECURE_DROP_MATERIALIZED_VIEW_CASCADE = "DROP MATERIALIZED VIEW IF EXISTS %s CASCADE;"
+
 	SECURE_DROP_TYPE = "DROP TYPE IF EXISTS %s;"
 
 	SECURE_DROP_FUNCTION = "DROP FUNCTION IF EXISTS %s(%s);"
 
+	SECURE_DROP_TABLE = "DROP TABLE IF EXISTS %s;"
+
+	SECURE_DROP_SEQUENCE = "DROP SEQUENCE IF EXISTS %s;"
+
+	SECURE_DROP_INDEX = "DROP INDEX IF EXISTS %s;"
+
+	SECURE_DROP_CONSTRAINT = "ALTER TABLE ONLY %s DROP CONSTRAINT IF EXISTS %s;"
+
+	SQL_ADD_CONSTRAINT = "ALTER TABLE ONLY %s\n    ADD CONSTRAINT %s %s;"
+
+	SQL_SEQUENCE_OWNED_BY = "ALTER SEQUENCE %s OWNED BY %s.%s;"
+
+	SQL_ADD_COLUMN = "ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s;"
+
+	SQL_DROP_COLUMN = "ALTER TABLE %s DROP COLUMN IF EXISTS %s;"
+
+	SQL_ALTER_COLUMN_TYPE = "ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s;"
+
+	SQL_ALTER_COLUMN_SET_DEFAULT = "ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s;"
+
+	SQL_ALTER_COLUMN_DROP_DEFAULT = "ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT;"
+
+	SQL_ALTER_COLUMN_SET_NOT_NULL = "ALTER TABLE %s ALTER COLUMN %s SET NOT NULL;"
+
+	SQL_ALTER_COLUMN_DROP_NOT_NULL = "ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL;"
+
+	SQL_ADD_ENUM_VALUE = "ALTER TYPE %s ADD VALUE IF NOT EXISTS '%s';"
+
+	SQL_ADD_ENUM_VALUE_BEFORE = "ALTER TYPE %s ADD VALUE IF NOT EXISTS '%s' BEFORE '%s';"
+
+	SQL_ADD_ENUM_VALUE_AFTER = "ALTER TYPE %s ADD VALUE IF NOT EXISTS '%s' AFTER '%s';"
+
+	SQL_REMOVE_ENUM_VALUE = "-- value '%s' can not be removed from enum %s, recreate the type manually"
+
 	SQL_CREATE_ENUM_OPEN = `
 DO $$ BEGIN
     CREATE TYPE %s AS ENUM (`
@@ -56,6 +110,10 @@ END $$;
 	SQL_INSERT_INTO_START = "INSERT INTO %s VALUES ("
 	SQL_INSERT_INTO_CLOSE = ");"
 
+	SQL_INSERT_INTO_COLUMNS = "INSERT INTO %s (%s) %sVALUES ("
+
+	OVERRIDING_SYSTEM_VALUE = "OVERRIDING SYSTEM VALUE "
+
 	QUERY_GET_PRIMARY_KEY = `
 SELECT
     kcu.column_name as key_column
@@ -68,11 +126,320 @@ WHERE tco.constraint_type = 'PRIMARY KEY'
     AND kcu.table_schema = '%s'
     AND kcu.table_name = '%s';`
 
+	QUERY_LIST_COLUMN = `
+SELECT
+    quote_ident(a.attname) AS column_name,
+    pg_catalog.format_type(a.atttypid, a.atttypmod) AS column_type,
+    CASE
+        WHEN a.attgenerated = '' AND a.attidentity = '' THEN COALESCE(pg_catalog.pg_get_expr(d.adbin, d.adrelid), '')
+        ELSE ''
+    END AS column_default,
+    a.attnotnull AS not_null,
+    CASE
+        WHEN a.attgenerated = 's' THEN 'GENERATED ALWAYS AS (' || pg_catalog.pg_get_expr(d.adbin, d.adrelid) || ') STORED'
+        WHEN a.attidentity = 'a' THEN 'GENERATED ALWAYS AS IDENTITY'
+        WHEN a.attidentity = 'd' THEN 'GENERATED BY DEFAULT AS IDENTITY'
+        ELSE ''
+    END AS column_generated
+FROM pg_catalog.pg_attribute a
+LEFT JOIN pg_catalog.pg_attrdef d
+    ON d.adrelid = a.attrelid
+    AND d.adnum = a.attnum
+WHERE a.attrelid = '%s'::regclass
+    AND a.attnum > 0
+    AND NOT a.attisdropped
+ORDER BY a.attnum;`
+
+	QUERY_LIST_SEQUENCE = `
+SELECT
+    quote_ident(n.nspname) || '.' || quote_ident(s.relname) AS sequence_name,
+    quote_ident(a.attname) AS column_name
+FROM pg_catalog.pg_class s
+JOIN pg_catalog.pg_namespace n
+    ON n.oid = s.relnamespace
+JOIN pg_catalog.pg_depend d
+    ON d.objid = s.oid
+    AND d.classid = 'pg_catalog.pg_class'::regclass
+    AND d.deptype = 'a'
+JOIN pg_catalog.pg_attribute a
+    ON a.attrelid = d.refobjid
+    AND a.attnum = d.refobjsubid
+WHERE s.relkind = 'S'
+    AND d.refobjid = '%s'::regclass
+ORDER BY s.relname;`
+
+	QUERY_LIST_INDEX = `
+SELECT
+    quote_ident(n.nspname) || '.' || quote_ident(ic.relname) AS index_name,
+    pg_catalog.pg_get_indexdef(i.indexrelid) AS definition
+FROM pg_catalog.pg_index i
+JOIN pg_catalog.pg_class ic
+    ON ic.oid = i.indexrelid
+JOIN pg_catalog.pg_namespace n
+    ON n.oid = ic.relnamespace
+WHERE i.indrelid = '%s'::regclass
+    AND NOT EXISTS (
+        SELECT 1
+        FROM pg_catalog.pg_constraint c
+        WHERE c.conindid = i.indexrelid
+            AND c.conrelid = i.indrelid
+            AND c.contype IN ('p', 'u', 'x')
+    )
+ORDER BY ic.relname;`
+
+	QUERY_LIST_CONSTRAINT = `
+SELECT
+    quote_ident(conname) AS constraint_name,
+    contype AS constraint_type,
+    pg_catalog.pg_get_constraintdef(oid) AS definition
+FROM pg_catalog.pg_constraint
+WHERE conrelid = '%s'::regclass
+    AND contype IN ('p', 'u', 'c', 'x', 'f')
+ORDER BY contype DESC,
+    conname;`
+
+	QUERY_LIST_ROW = "SELECT %s AS row_values, %s AS key_value FROM %s t;"
+
+	QUERY_OBJECT = `
+WITH nodes AS (
+    SELECT
+        c.oid,
+        'pg_catalog.pg_class'::regclass::oid AS classid,
+        CASE c.relkind
+            WHEN 'v' THEN 'view'
+            WHEN 'm' THEN 'materialized_view'
+            ELSE 'table'
+        END AS kind,
+        LOWER(c.relname::text) AS name
+    FROM pg_catalog.pg_class c
+    JOIN pg_catalog.pg_namespace n
+        ON n.oid = c.relnamespace
+    WHERE n.nspname = '%[1]s'
+        AND c.relkind IN ('r', 'p', 'v', 'm')
+    UNION ALL
+    SELECT
+        p.oid,
+        'pg_catalog.pg_proc'::regclass::oid,
+        'function',
+        p.proname::text
+    FROM pg_catalog.pg_proc p
+    JOIN pg_catalog.pg_namespace n
+        ON n.oid = p.pronamespace
+    WHERE n.nspname = '%[1]s'
+    UNION ALL
+    SELECT
+        t.oid,
+        'pg_catalog.pg_type'::regclass::oid,
+        'enum',
+        pg_catalog.format_type(t.oid, NULL)
+    FROM pg_catalog.pg_type t
+    JOIN pg_catalog.pg_namespace n
+        ON n.oid = t.typnamespace
+    WHERE n.nspname = '%[1]s'
+        AND t.typtype = 'e'
+)`
+
+	QUERY_LIST_OBJECT = QUERY_OBJECT + `
+SELECT
+    kind,
+    name,
+    COUNT(1) AS total
+FROM nodes
+GROUP BY kind,
+    name
+ORDER BY kind,
+    name;`
+
+	QUERY_LIST_DEPENDENCY = QUERY_OBJECT + `,
+owners AS (
+    SELECT
+        classid,
+        oid AS objid,
+        classid AS owner_class,
+        oid AS owner
+    FROM nodes
+    UNION ALL
+    SELECT
+        'pg_catalog.pg_rewrite'::regclass::oid,
+        r.oid,
+        'pg_catalog.pg_class'::regclass::oid,
+        r.ev_class
+    FROM pg_catalog.pg_rewrite r
+    UNION ALL
+    SELECT
+        'pg_catalog.pg_attrdef'::regclass::oid,
+        a.oid,
+        'pg_catalog.pg_class'::regclass::oid,
+        a.adrelid
+    FROM pg_catalog.pg_attrdef a
+    UNION ALL
+    SELECT
+        'pg_catalog.pg_constraint'::regclass::oid,
+        c.oid,
+        'pg_catalog.pg_class'::regclass::oid,
+        c.conrelid
+    FROM pg_catalog.pg_constraint c
+    WHERE c.contype = 'c'
+        AND c.conrelid <> 0
+    UNION ALL
+    SELECT
+        'pg_catalog.pg_type'::regclass::oid,
+        t.oid,
+        'pg_catalog.pg_type'::regclass::oid,
+        t.typelem
+    FROM pg_catalog.pg_type t
+    WHERE t.typelem <> 0
+    UNION ALL
+    SELECT
+        'pg_catalog.pg_type'::regclass::oid,
+        t.oid,
+        'pg_catalog.pg_class'::regclass::oid,
+        t.typrelid
+    FROM pg_catalog.pg_type t
+    WHERE t.typrelid <> 0
+)
+SELECT DISTINCT
+    o.kind,
+    o.name,
+    r.kind AS referenced_kind,
+    r.name AS referenced_name
+FROM pg_catalog.pg_depend d
+JOIN owners ow
+    ON ow.classid = d.classid
+    AND ow.objid = d.objid
+JOIN nodes o
+    ON o.classid = ow.owner_class
+    AND o.oid = ow.owner
+JOIN owners rw
+    ON rw.classid = d.refclassid
+    AND rw.objid = d.refobjid
+JOIN nodes r
+    ON r.classid = rw.owner_class
+    AND r.oid = rw.owner
+WHERE d.deptype = 'n'
+    AND (o.kind, o.name) <> (r.kind, r.name)
+ORDER BY o.kind,
+    o.name;`
+
+	SQL_CREATE_HISTORY = `
+CREATE TABLE IF NOT EXISTS %[1]s.kmt_histories (
+    id BIGSERIAL PRIMARY KEY,
+    version BIGINT NOT NULL,
+    direction VARCHAR(16) NOT NULL,
+    file_name TEXT NOT NULL,
+    checksum CHAR(64) NOT NULL,
+    os_user TEXT NOT NULL,
+    host TEXT NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL,
+    success BOOLEAN NOT NULL,
+    message TEXT NOT NULL DEFAULT ''
+);
+ALTER TABLE %[1]s.kmt_histories ALTER COLUMN direction TYPE VARCHAR(16);`
+
+	SQL_INSERT_HISTORY = `
+INSERT INTO %s.kmt_histories (version, direction, file_name, checksum, os_user, host, started_at, finished_at, success, message)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);`
+
+	QUERY_LIST_HISTORY = `
+SELECT
+    version,
+    direction,
+    file_name,
+    checksum,
+    os_user,
+    host,
+    started_at,
+    finished_at,
+    success,
+    message
+FROM %s.kmt_histories
+ORDER BY id;`
+
+	QUERY_HISTORY_EXISTS = "SELECT to_regclass($1) IS NOT NULL;"
+
+	QUERY_MIGRATION_TABLE_EXISTS = "SELECT to_regclass($1) IS NOT NULL"
+
+	QUERY_SCHEMA_EXISTS = "SELECT EXISTS (SELECT 1 FROM pg_catalog.pg_namespace WHERE nspname = $1)"
+
+	SQL_RENAME_SCHEMA = "ALTER SCHEMA %s RENAME TO %s"
+
+	SQL_MOVE_TABLE = "ALTER TABLE %s.%s SET SCHEMA %s"
+
+	QUERY_MIGRATION_VERSION = "SELECT version, dirty FROM %s.schema_migrations LIMIT 1"
+
+	SQL_CLEAR_MIGRATION_VERSION = "TRUNCATE %s.schema_migrations"
+
+	SQL_SET_MIGRATION_VERSION = "INSERT INTO %s.schema_migrations (version, dirty) VALUES ($1, $2)"
+
+	SQL_CREATE_LOCK_SCHEMA = "CREATE SCHEMA IF NOT EXISTS kmt;"
+
+	SQL_CREATE_LOCK = `
+CREATE TABLE IF NOT EXISTS kmt.kmt_locks (
+    target TEXT NOT NULL,
+    schema_name TEXT NOT NULL,
+    nodes TEXT[] NOT NULL,
+    token TEXT NOT NULL,
+    owner TEXT NOT NULL,
+    acquired_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (target, schema_name)
+);`
+
+	SQL_LOCK_LOCK_TABLE = "LOCK TABLE kmt.kmt_locks IN SHARE ROW EXCLUSIVE MODE;"
+
+	SQL_CLEAR_EXPIRED_LOCK = "DELETE FROM kmt.kmt_locks WHERE expires_at < now();"
+
+	SQL_ACQUIRE_LOCK = `
+INSERT INTO kmt.kmt_locks (target, schema_name, nodes, token, owner, acquired_at, expires_at)
+VALUES ($1, $2, $3, $4, $5, now(), now() + make_interval(secs => $6));`
+
+	SQL_REFRESH_LOCK = "UPDATE kmt.kmt_locks SET expires_at = now() + make_interval(secs => $4) WHERE target = $1 AND schema_name = $2 AND token = $3;"
+
+	SQL_RELEASE_LOCK = "DELETE FROM kmt.kmt_locks WHERE target = $1 AND schema_name = $2 AND token = $3;"
+
+	SQL_FORCE_RELEASE_LOCK = "DELETE FROM kmt.kmt_locks WHERE target = $1 AND schema_name = $2;"
+
+	QUERY_LIST_LOCK = `
+SELECT
+    target,
+    schema_name,
+    nodes,
+    owner,
+    acquired_at,
+    expires_at,
+    expires_at < now() AS expired
+FROM kmt.kmt_locks
+ORDER BY target, schema_name;`
+
+	QUERY_CONFLICT_LOCK = `
+SELECT
+    target,
+    schema_name,
+    nodes,
+    owner,
+    acquired_at,
+    expires_at,
+    expires_at < now() AS expired
+FROM kmt.kmt_locks
+WHERE schema_name = $1 AND nodes && $2::TEXT[]
+LIMIT 1;`
+
+	QUERY_LIST_REPEATABLE = `
+SELECT DISTINCT ON (file_name)
+    file_name,
+    checksum
+FROM %s.kmt_histories
+WHERE direction = $1
+    AND success
+ORDER BY file_name,
+    id DESC;`
+
 	QUERY_LIST_FUNCTION = `
 SELECT
     p.proname AS function_name,
     pg_get_functiondef(p.oid) AS function_definition,
-    pg_get_function_arguments(p.oid) AS function_paramters
+    pg_get_function_identity_arguments(p.oid) AS function_paramters
 FROM pg_proc p
 JOIN pg_namespace n
     ON n.oid = p.pronamespace
@@ -85,7 +452,7 @@ SELECT
         ARRAY( SELECT e.enumlabel
                 FROM pg_catalog.pg_enum e
                 WHERE e.enumtypid = t.oid
-                ORDER BY e.oid ), '#'
+                ORDER BY e.enumsortorder ), '#'
         ) AS values
 FROM pg_catalog.pg_type t
 LEFT JOIN pg_catalog.pg_namespace n
@@ -107,6 +474,14 @@ WHERE ( t.typrelid = 0
     AND n.nspname = '%s'
 ORDER BY name;`
 
+	QUERY_LIST_SCHEMA = `
+SELECT
+    nspname AS schema_name
+FROM pg_namespace
+WHERE nspname NOT LIKE 'pg\_%'
+    AND nspname <> 'information_schema'
+ORDER BY nspname;`
+
 	QUERY_LIST_TABLE = `
 SELECT
     LOWER(table_name) AS table_name
@@ -0,0 +1,337 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type (
+	snapshot struct {
+		db *sql.DB
+	}
+
+	Column struct {
+		Name      string
+		Type      string
+		Default   string
+		NotNull   bool
+		Generated string
+	}
+
+	Snapshot struct {
+		Schema            string
+		Tables            map[string][]Column
+		Indexes           map[string]string
+		Constraints       map[string]string
+		Enums             map[string][]string
+		Functions         map[string]string
+		Views             map[string]string
+		MaterializedViews map[string]string
+	}
+
+	Difference struct {
+		Kind   string
+		Table  string
+		Name   string
+		Source string
+		Target string
+	}
+)
+
+func NewSnapshot(db *sql.DB) snapshot {
+	return snapshot{db: db}
+}
+
+func (c Column) String() string {
+	definition := c.Type
+	if c.Default != "" {
+		definition = fmt.Sprintf("%s DEFAULT %s", definition, c.Default)
+	}
+
+	if c.NotNull {
+		definition = fmt.Sprintf("%s NOT NULL", definition)
+	}
+
+	if c.Generated != "" {
+		definition = fmt.Sprintf("%s %s", definition, c.Generated)
+	}
+
+	return definition
+}
+
+func (s snapshot) Take(schema string) (Snapshot, error) {
+	result := Snapshot{
+		Schema:            schema,
+		Tables:            map[string][]Column{},
+		Indexes:           map[string]string{},
+		Constraints:       map[string]string{},
+		Enums:             map[string][]string{},
+		Functions:         map[string]string{},
+		Views:             map[string]string{},
+		MaterializedViews: map[string]string{},
+	}
+
+	tables, err := s.list(fmt.Sprintf(QUERY_LIST_TABLE, schema), 1)
+	if err != nil {
+		return result, err
+	}
+
+	for _, t := range tables {
+		if t[0] == HISTORY_TABLE {
+			continue
+		}
+
+		name := fmt.Sprintf("%s.%s", schema, t[0])
+
+		columns, err := s.list(fmt.Sprintf(QUERY_LIST_COLUMN, name), 5)
+		if err != nil {
+			return result, err
+		}
+
+		result.Tables[t[0]] = []Column{}
+		for _, c := range columns {
+			result.Tables[t[0]] = append(result.Tables[t[0]], Column{Name: c[0], Type: c[1], Default: c[2], NotNull: c[3] == "true", Generated: c[4]})
+		}
+
+		indexes, err := s.list(fmt.Sprintf(QUERY_LIST_INDEX, name), 2)
+		if err != nil {
+			return result, err
+		}
+
+		for _, i := range indexes {
+			result.Indexes[i[0]] = i[1]
+		}
+
+		constraints, err := s.list(fmt.Sprintf(QUERY_LIST_CONSTRAINT, name), 3)
+		if err != nil {
+			return result, err
+		}
+
+		for _, c := range constraints {
+			result.Constraints[fmt.Sprintf("%s.%s", t[0], c[0])] = c[2]
+		}
+	}
+
+	enums, err := s.list(fmt.Sprintf(QUERY_LIST_ENUM, schema), 2)
+	if err != nil {
+		return result, err
+	}
+
+	for _, e := range enums {
+		result.Enums[e[0]] = strings.Split(e[1], "#")
+	}
+
+	functions, err := s.list(fmt.Sprintf(QUERY_LIST_FUNCTION, schema), 3)
+	if err != nil {
+		return result, err
+	}
+
+	for _, f := range functions {
+		result.Functions[fmt.Sprintf("%s(%s)", f[0], f[2])] = f[1]
+	}
+
+	views, err := s.list(fmt.Sprintf(QUERY_LIST_VIEW, schema), 2)
+	if err != nil {
+		return result, err
+	}
+
+	for _, v := range views {
+		result.Views[v[0]] = v[1]
+	}
+
+	mViews, err := s.list(fmt.Sprintf(QUERY_MATERIALIZED_VIEW, schema), 2)
+	if err != nil {
+		return result, err
+	}
+
+	for _, v := range mViews {
+		result.MaterializedViews[v[0]] = v[1]
+	}
+
+	return result, nil
+}
+
+func (s snapshot) list(query string, nColumn int) ([][]string, error) {
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	result := [][]string{}
+	for rows.Next() {
+		values := make([]string, nColumn)
+		pointers := make([]interface{}, nColumn)
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		err = rows.Scan(pointers...)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, values)
+	}
+
+	return result, rows.Err()
+}
+
+func (s Snapshot) Compare(target Snapshot) []Difference {
+	differences := []Difference{}
+
+	sTables := []string{}
+	for k := range s.Tables {
+		sTables = append(sTables, k)
+	}
+
+	tTables := []string{}
+	for k := range target.Tables {
+		tTables = append(tTables, k)
+	}
+
+	for _, t := range union(sTables, tTables) {
+		sTable, sOk := s.Tables[t]
+		tTable, tOk := target.Tables[t]
+		if !sOk || !tOk {
+			differences = append(differences, Difference{
+				Kind:   OBJECT_TABLE,
+				Name:   t,
+				Source: exists(sOk),
+				Target: exists(tOk),
+			})
+
+			continue
+		}
+
+		sNames := []string{}
+		sColumns := map[string]Column{}
+		for _, c := range sTable {
+			sNames = append(sNames, c.Name)
+			sColumns[c.Name] = c
+		}
+
+		tNames := []string{}
+		tColumns := map[string]Column{}
+		for _, c := range tTable {
+			tNames = append(tNames, c.Name)
+			tColumns[c.Name] = c
+		}
+
+		for _, c := range union(sNames, tNames) {
+			sColumn, sOk := sColumns[c]
+			tColumn, tOk := tColumns[c]
+
+			var sDefinition, tDefinition string
+			if sOk {
+				sDefinition = sColumn.String()
+			}
+
+			if tOk {
+				tDefinition = tColumn.String()
+			}
+
+			if sDefinition == tDefinition {
+				continue
+			}
+
+			differences = append(differences, Difference{
+				Kind:   OBJECT_COLUMN,
+				Table:  t,
+				Name:   c,
+				Source: sDefinition,
+				Target: tDefinition,
+			})
+		}
+	}
+
+	differences = append(differences, compare(OBJECT_INDEX, s.Indexes, target.Indexes)...)
+	differences = append(differences, compare(OBJECT_CONSTRAINT, s.Constraints, target.Constraints)...)
+
+	sEnums := map[string]string{}
+	for k, v := range s.Enums {
+		sEnums[k] = strings.Join(v, ", ")
+	}
+
+	tEnums := map[string]string{}
+	for k, v := range target.Enums {
+		tEnums[k] = strings.Join(v, ", ")
+	}
+
+	differences = append(differences, compare(OBJECT_ENUM, sEnums, tEnums)...)
+	differences = append(differences, compare(OBJECT_FUNCTION, s.Functions, target.Functions)...)
+	differences = append(differences, compare(OBJECT_VIEW, s.Views, target.Views)...)
+	differences = append(differences, compare(OBJECT_MATERIALIZED_VIEW, s.MaterializedViews, target.MaterializedViews)...)
+
+	return differences
+}
+
+func compare(kind string, source map[string]string, target map[string]string) []Difference {
+	differences := []Difference{}
+	for _, k := range union(names(source), names(target)) {
+		sDefinition, sOk := source[k]
+		tDefinition, tOk := target[k]
+		if sOk && tOk && strings.TrimSpace(sDefinition) == strings.TrimSpace(tDefinition) {
+			continue
+		}
+
+		difference := Difference{Kind: kind, Name: k}
+		if kind == OBJECT_CONSTRAINT {
+			names := strings.SplitN(k, ".", 2)
+			difference.Table = names[0]
+			difference.Name = names[1]
+		}
+
+		if sOk {
+			difference.Source = sDefinition
+		}
+
+		if tOk {
+			difference.Target = tDefinition
+		}
+
+		differences = append(differences, difference)
+	}
+
+	return differences
+}
+
+func names(values map[string]string) []string {
+	result := make([]string, 0, len(values))
+	for k := range values {
+		result = append(result, k)
+	}
+
+	return result
+}
+
+func union(source []string, target []string) []string {
+	unique := map[string]bool{}
+	for _, v := range source {
+		unique[v] = true
+	}
+
+	for _, v := range target {
+		unique[v] = true
+	}
+
+	result := make([]string, 0, len(unique))
+	for k := range unique {
+		result = append(result, k)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+func exists(ok bool) string {
+	if ok {
+		return "exists"
+	}
+
+	return ""
+}
@@ -7,7 +7,6 @@ import (
 	"os"
 	"os/exec"
 	"regexp"
-	"strconv"
 	"strings"
 )
 
@@ -31,7 +30,195 @@ func NewTable(command string, config config.Connection, db *sql.DB) Table {
 	return Table{command: command, config: config, db: db}
 }
 
-func (t Table) Generate(name string, schemaOnly bool) Ddl {
+func (t Table) Generate(name string, schemaOnly bool) (Ddl, error) {
+	if t.command == "" {
+		return t.catalog(name, schemaOnly)
+	}
+
+	return t.dump(name, schemaOnly)
+}
+
+func (t Table) catalog(name string, schemaOnly bool) (Ddl, error) {
+	var upScript strings.Builder
+	var downScript strings.Builder
+	var upReferenceScript strings.Builder
+	var downReferenceScript strings.Builder
+	var upForeignScript strings.Builder
+	var downForeignScript strings.Builder
+	var insertScript strings.Builder
+	var deleteScript strings.Builder
+
+	sequences, err := t.sequences(name)
+	if err != nil {
+		return Ddl{}, err
+	}
+
+	for _, s := range sequences {
+		upScript.WriteString(fmt.Sprintf("%s %s;\n", SECURE_CREATE_SEQUENCE, s[0]))
+	}
+
+	table, err := t.createTable(name)
+	if err != nil {
+		return Ddl{}, err
+	}
+
+	upScript.WriteString(table)
+
+	for _, s := range sequences {
+		upScript.WriteString(fmt.Sprintf(SQL_SEQUENCE_OWNED_BY, s[0], name, s[1]))
+		upScript.WriteString("\n")
+	}
+
+	downScript.WriteString(fmt.Sprintf(SECURE_DROP_TABLE, name))
+	downScript.WriteString("\n")
+	for _, s := range sequences {
+		downScript.WriteString(fmt.Sprintf(SECURE_DROP_SEQUENCE, s[0]))
+		downScript.WriteString("\n")
+	}
+
+	rows, err := t.db.Query(fmt.Sprintf(QUERY_LIST_INDEX, name))
+	if err != nil {
+		return Ddl{}, err
+	}
+
+	for rows.Next() {
+		var index string
+		var definition string
+		err = rows.Scan(&index, &definition)
+		if err != nil {
+			rows.Close()
+
+			return Ddl{}, err
+		}
+
+		definition = strings.Replace(definition, CREATE_UNIQUE_INDEX, SECURE_CREATE_UNIQUE_INDEX, 1)
+		definition = strings.Replace(definition, CREATE_INDEX, SECURE_CREATE_INDEX, 1)
+
+		upScript.WriteString(definition)
+		upScript.WriteString(";\n")
+	}
+
+	rows.Close()
+
+	rows, err = t.db.Query(fmt.Sprintf(QUERY_LIST_CONSTRAINT, name))
+	if err != nil {
+		return Ddl{}, err
+	}
+
+	for rows.Next() {
+		var constraint string
+		var kind string
+		var definition string
+		err = rows.Scan(&constraint, &kind, &definition)
+		if err != nil {
+			rows.Close()
+
+			return Ddl{}, err
+		}
+
+		if kind == "f" {
+			upForeignScript.WriteString(fmt.Sprintf(SQL_ADD_CONSTRAINT, name, constraint, definition))
+			upForeignScript.WriteString("\n")
+			downForeignScript.WriteString(fmt.Sprintf(SECURE_DROP_CONSTRAINT, name, constraint))
+			downForeignScript.WriteString("\n")
+
+			continue
+		}
+
+		upReferenceScript.WriteString(fmt.Sprintf(SQL_ADD_CONSTRAINT, name, constraint, definition))
+		upReferenceScript.WriteString("\n")
+		downReferenceScript.WriteString(fmt.Sprintf(SECURE_DROP_CONSTRAINT, name, constraint))
+		downReferenceScript.WriteString("\n")
+	}
+
+	rows.Close()
+
+	if !schemaOnly {
+		primaryKey, err := t.primaryKey(name)
+		if err != nil {
+			return Ddl{}, err
+		}
+
+		if primaryKey == name {
+			primaryKey = ""
+		}
+
+		key := "''"
+		if primaryKey != "" {
+			key = fmt.Sprintf("quote_nullable(pg_catalog.row_to_json(t)->>'%s')", primaryKey)
+		}
+
+		names := []string{}
+		expressions := []string{}
+		overriding := ""
+		columns, err := t.columns(name)
+		if err != nil {
+			return Ddl{}, err
+		}
+
+		for _, c := range columns {
+			if strings.HasPrefix(c.Generated, "GENERATED ALWAYS AS (") {
+				continue
+			}
+
+			if c.Generated == "GENERATED ALWAYS AS IDENTITY" {
+				overriding = OVERRIDING_SYSTEM_VALUE
+			}
+
+			names = append(names, c.Name)
+			expressions = append(expressions, fmt.Sprintf("quote_nullable(t.%s::text)", c.Name))
+		}
+
+		rows, err = t.db.Query(fmt.Sprintf(QUERY_LIST_ROW, strings.Join(expressions, " || ', ' || "), key, name))
+		if err != nil {
+			return Ddl{}, err
+		}
+
+		for rows.Next() {
+			var values string
+			var value string
+			err = rows.Scan(&values, &value)
+			if err != nil {
+				rows.Close()
+
+				return Ddl{}, err
+			}
+
+			insertScript.WriteString(fmt.Sprintf(SQL_INSERT_INTO_COLUMNS, name, strings.Join(names, ", "), overriding))
+			insertScript.WriteString(values)
+			insertScript.WriteString(SQL_INSERT_INTO_CLOSE)
+			insertScript.WriteString("\n")
+
+			if primaryKey != "" {
+				deleteScript.WriteString(fmt.Sprintf("DELETE FROM %s WHERE %s = %s;\n", name, primaryKey, value))
+			}
+		}
+
+		rows.Close()
+	}
+
+	return Ddl{
+		Name: strings.Replace(name, ".", "_", -1),
+		Definition: Migration{
+			UpScript:   upScript.String(),
+			DownScript: downScript.String(),
+		},
+		Insert: Migration{
+			UpScript:   insertScript.String(),
+			DownScript: deleteScript.String(),
+		},
+		Reference: Migration{
+			UpScript:   upReferenceScript.String(),
+			DownScript: downReferenceScript.String(),
+		},
+		ForeignKey: Migration{
+			UpScript:   upForeignScript.String(),
+			DownScript: downForeignScript.String(),
+		},
+	}, nil
+}
+
+func (t Table) dump(name string, schemaOnly bool) (Ddl, error) {
 	options := []string{
 		"--no-comments",
 		"--no-publications",
@@ -45,11 +232,8 @@ func (t Table) Generate(name string, schemaOnly bool) Ddl {
 		"--no-privileges",
 		"--no-blobs",
 		"--clean",
-		"--username", t.config.User,
-		"--port", strconv.Itoa(t.config.Port),
-		"--host", t.config.Host,
 		"--table", name,
-		t.config.Name,
+		"--dbname", t.config.Conninfo(),
 	}
 
 	if schemaOnly {
@@ -60,7 +244,7 @@ func (t Table) Generate(name string, schemaOnly bool) Ddl {
 
 	cli := exec.Command(t.command, options...)
 	cli.Env = os.Environ()
-	cli.Env = append(cli.Env, fmt.Sprintf("PGPASSWORD=%s", t.config.Password))
+	cli.Env = append(cli.Env, fmt.Sprintf("PGPASSWORD=%s", t.config.Secret()))
 
 	var upScript strings.Builder
 	var downScript strings.Builder
@@ -73,12 +257,20 @@ func (t Table) Generate(name string, schemaOnly bool) Ddl {
 	var skip bool = false
 	var waitForSemicolon bool = false
 
-	primaryKey := t.primaryKey(name)
+	primaryKey, err := t.primaryKey(name)
+	if err != nil {
+		return Ddl{}, err
+	}
+
 	if primaryKey == name {
 		primaryKey = ""
 	}
 
-	result, _ := cli.CombinedOutput()
+	result, err := cli.CombinedOutput()
+	if err != nil {
+		return Ddl{}, fmt.Errorf("dump of table %s failed: %w: %s", name, err, strings.TrimSpace(string(result)))
+	}
+
 	lines := strings.Split(string(result), "\n")
 	for n, line := range lines {
 		if t.skip(line) || skip {
@@ -202,28 +394,96 @@ func (t Table) Generate(name string, schemaOnly bool) Ddl {
 			UpScript:   upForeignScript.String(),
 			DownScript: downForeignScript.String(),
 		},
+	}, nil
+}
+
+func (t Table) createTable(name string) (string, error) {
+	columns, err := t.columns(name)
+	if err != nil {
+		return "", err
 	}
+
+	return createTable(name, columns), nil
 }
 
-func (t Table) primaryKey(name string) string {
+func (t Table) columns(name string) ([]Column, error) {
+	columns := []Column{}
+	rows, err := t.db.Query(fmt.Sprintf(QUERY_LIST_COLUMN, name))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		column := Column{}
+		err = rows.Scan(&column.Name, &column.Type, &column.Default, &column.NotNull, &column.Generated)
+		if err != nil {
+			return nil, err
+		}
+
+		columns = append(columns, column)
+	}
+
+	return columns, rows.Err()
+}
+
+func createTable(name string, columns []Column) string {
+	var ddl strings.Builder
+
+	ddl.WriteString(fmt.Sprintf("%s %s (\n", SECURE_CREATE_TABLE, name))
+
+	definitions := []string{}
+	for _, c := range columns {
+		definitions = append(definitions, fmt.Sprintf("    %s %s", c.Name, c.String()))
+	}
+
+	ddl.WriteString(strings.Join(definitions, ",\n"))
+	ddl.WriteString("\n);\n")
+
+	return ddl.String()
+}
+
+func (t Table) sequences(name string) ([][2]string, error) {
+	sequences := [][2]string{}
+	rows, err := t.db.Query(fmt.Sprintf(QUERY_LIST_SEQUENCE, name))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var sequence string
+		var column string
+		err = rows.Scan(&sequence, &column)
+		if err != nil {
+			return nil, err
+		}
+
+		sequences = append(sequences, [2]string{sequence, column})
+	}
+
+	return sequences, rows.Err()
+}
+
+func (t Table) primaryKey(name string) (string, error) {
 	tables := strings.Split(name, ".")
 	rows, err := t.db.Query(fmt.Sprintf(QUERY_GET_PRIMARY_KEY, tables[0], tables[1]))
 	if err != nil {
-		fmt.Println(err.Error())
-
-		return ""
+		return "", err
 	}
 
+	defer rows.Close()
+
 	for rows.Next() {
 		err = rows.Scan(&name)
 		if err != nil {
-			fmt.Println(err.Error())
-
-			break
+			return "", err
 		}
 	}
 
-	return name
+	return name, rows.Err()
 }
 
 func (Table) keyValue(line string, name string, between bool) string {